@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"GoUnder/query"
+	"GoUnder/utils"
+
+	"github.com/spf13/cobra"
+)
+
+const queryCacheDir = "query_cache"
+
+var (
+	noCache      bool
+	refreshCache bool
+	cacheTTL     = 24 * time.Hour
+	purgeExpired bool
+	cacheHits    int
+	cacheMisses  int
+)
+
+// cacheEntry 是缓存目录中的一条查询结果
+type cacheEntry struct {
+	Engine    string          `json:"engine"`
+	Query     string          `json:"query"`
+	Fields    string          `json:"fields"`
+	CreatedAt time.Time       `json:"created_at"`
+	Count     int             `json:"count"`
+	Results   json.RawMessage `json:"results"`
+	file      string
+}
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local query result cache.",
+}
+
+var cacheLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List cached queries.",
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := loadCacheEntries()
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		if len(entries) == 0 {
+			fmt.Println("❌ Query cache is empty.")
+			return
+		}
+		fmt.Printf("\n✅ %d cached query(s):\n", len(entries))
+		for _, e := range entries {
+			state := "valid"
+			if time.Since(e.CreatedAt) > cacheTTL {
+				state = "expired"
+			}
+			fmt.Printf("- [%s] %s, %d result(s), age %s, %s\n", e.Engine, shorten(e.Query, 80), e.Count, time.Since(e.CreatedAt).Round(time.Second), state)
+		}
+	},
+}
+
+var cachePurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Remove cached queries.",
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := loadCacheEntries()
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		removed := 0
+		for _, e := range entries {
+			if purgeExpired && time.Since(e.CreatedAt) <= cacheTTL {
+				continue
+			}
+			if err := os.Remove(e.file); err == nil {
+				removed++
+			}
+		}
+		fmt.Printf("✅ %d cached query(s) removed.\n", removed)
+	},
+}
+
+// cacheDir 返回查询缓存目录，与 CDN IP 段缓存位于同一配置目录
+func cacheDir() (string, error) {
+	dir, err := utils.CacheFilePath(queryCacheDir)
+	if err != nil {
+		return "", err
+	}
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+// normalizeQuery 规范化查询语句：FOFA 语法重新格式化，其余引擎折叠多余空白
+func normalizeQuery(engine string, q string) string {
+	if engine == "fofa" {
+		if n, err := query.Parse(q); err == nil {
+			if s, err := query.Render(n, query.FOFA); err == nil {
+				return s
+			}
+		}
+	}
+	return strings.Join(strings.Fields(q), " ")
+}
+
+func cacheFile(engine string, q string, fields string) (string, string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", "", err
+	}
+	normalized := normalizeQuery(engine, q)
+	sum := sha256.Sum256([]byte(engine + "\n" + normalized + "\n" + fields))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), normalized, nil
+}
+
+// cacheGet 读取未过期的缓存结果到 v，--no-cache 与 --refresh 时跳过
+func cacheGet(engine string, q string, fields string, v interface{}) bool {
+	if noCache || refreshCache {
+		return false
+	}
+	path, normalized, err := cacheFile(engine, q, fields)
+	if err != nil {
+		return false
+	}
+	entry, ok := readCacheEntry(path)
+	if !ok || json.Unmarshal(entry.Results, v) != nil {
+		cacheMisses++
+		if verbose {
+			fmt.Printf("[cache] miss [%s] %s\n", engine, shorten(normalized, 60))
+		}
+		return false
+	}
+	cacheHits++
+	fmt.Printf("[cache] hit  [%s] %s (age %s)\n", engine, shorten(normalized, 60), time.Since(entry.CreatedAt).Round(time.Second))
+	return true
+}
+
+// cacheFresh 判断查询是否有未过期的缓存，不计入命中统计，供执行前估算额度
+func cacheFresh(engine string, q string, fields string) bool {
+	if noCache || refreshCache {
+		return false
+	}
+	path, _, err := cacheFile(engine, q, fields)
+	if err != nil {
+		return false
+	}
+	_, ok := readCacheEntry(path)
+	return ok
+}
+
+// readCacheEntry 读取缓存文件，文件不存在、损坏或已过期时返回 false
+func readCacheEntry(path string) (cacheEntry, bool) {
+	var entry cacheEntry
+	data, err := os.ReadFile(path)
+	if err != nil || json.Unmarshal(data, &entry) != nil || time.Since(entry.CreatedAt) > cacheTTL {
+		return entry, false
+	}
+	return entry, true
+}
+
+// cachePut 写入查询结果，--no-cache 时跳过
+func cachePut(engine string, q string, fields string, count int, v interface{}) {
+	if noCache {
+		return
+	}
+	path, normalized, err := cacheFile(engine, q, fields)
+	if err != nil {
+		return
+	}
+	results, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	data, _ := json.MarshalIndent(cacheEntry{
+		Engine:    engine,
+		Query:     normalized,
+		Fields:    fields,
+		CreatedAt: time.Now(),
+		Count:     count,
+		Results:   results,
+	}, "", "  ")
+	_ = os.WriteFile(path, data, 0644)
+}
+
+// cachedSearch 优先从缓存读取引擎查询结果，查询出错时不写入缓存
+func cachedSearch(e SearchEngine, q string) ([]SearchResult, error) {
+	fields := cacheFields(e)
+	var results []SearchResult
+	if cacheGet(e.Name(), q, fields, &results) {
+		return results, nil
+	}
+	results, err := e.Search(q)
+	if err == nil {
+		cachePut(e.Name(), q, fields, len(results), results)
+	}
+	return results, err
+}
+
+// cacheFields 描述影响查询结果的字段与翻页参数
+func cacheFields(e SearchEngine) string {
+	if e.Name() == "fofa" {
+		return fmt.Sprintf("%s;size=%d;pages=%d;all=%v", fofaSearchFields(), fofaSize, fofaMaxPages, fofaAll)
+	}
+	if k, ok := e.(cacheKeyer); ok {
+		return "cdn;" + k.CacheKey()
+	}
+	return "cdn"
+}
+
+func loadCacheEntries() ([]cacheEntry, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var entries []cacheEntry
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		var e cacheEntry
+		if json.Unmarshal(data, &e) != nil {
+			continue
+		}
+		e.file = f
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CreatedAt.After(entries[j].CreatedAt) })
+	return entries, nil
+}
+
+func printCacheStats() {
+	if cacheHits+cacheMisses > 0 {
+		fmt.Printf("[+] Query cache: %d hit(s), %d miss(es)\n", cacheHits, cacheMisses)
+	}
+}
+
+func shorten(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "..."
+}
+
+func init() {
+	cacheCmd.PersistentFlags().DurationVarP(&cacheTTL, "cache-ttl", "", 24*time.Hour, "cache entries older than this are expired")
+	cachePurgeCmd.Flags().BoolVarP(&purgeExpired, "expired", "", false, "only remove expired entries")
+	cacheCmd.AddCommand(cacheLsCmd, cachePurgeCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Candidate 是按 IP 聚合后的疑似源站，CLI 输出、日志与 Web API 共用
+type Candidate struct {
+	IP         string              `json:"ip"`
+	Ports      []string            `json:"ports"`
+	Hostnames  []string            `json:"hostnames"`
+	Org        string              `json:"org"`
+	Country    string              `json:"country"`
+	Region     string              `json:"region"`
+	City       string              `json:"city"`
+	Sources    []string            `json:"sources"`
+	Strategies []string            `json:"strategies"`
+	Queries    []string            `json:"queries"`
+	FirstSeen  *time.Time          `json:"firstSeen,omitempty"`
+	LastSeen   *time.Time          `json:"lastSeen,omitempty"`
+	Extra      map[string][]string `json:"extra,omitempty"`
+	// CoHosts 为引擎或 InternetDB 记录的同一 IP 上的全部主机名，用于判断共享主机
+	CoHosts []string `json:"coHosts,omitempty"`
+	// Verification 为 --verify 直连验证的结果
+	Verification *Verification `json:"verification,omitempty"`
+	// Certs 为 --verify-tls 以目标 SNI 获取的证书
+	Certs []CertInfo `json:"certs,omitempty"`
+	// Score 与 Reasons 为 scoreCandidates 的打分及依据
+	Score   int      `json:"score"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// 各引擎返回的扫描时间格式
+var seenLayouts = []string{
+	"2006-01-02 15:04:05",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999",
+	"2006-01-02",
+}
+
+func parseSeen(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range seenLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// groupCandidates 将多个引擎、多个策略的结果按 IP 聚合，保持首次出现的顺序
+func groupCandidates(results []SearchResult) []Candidate {
+	index := make(map[string]int)
+	var candidates []Candidate
+	for _, r := range results {
+		if r.IP == "" {
+			continue
+		}
+		r.IP = normalizeIP(r.IP)
+		i, ok := index[r.IP]
+		if !ok {
+			i = len(candidates)
+			index[r.IP] = i
+			candidates = append(candidates, Candidate{IP: r.IP})
+		}
+		c := &candidates[i]
+		c.Ports = appendUnique(c.Ports, r.Port)
+		// Shodan 等引擎的 host 可能包含多个以空格或逗号分隔的主机名
+		for _, h := range strings.FieldsFunc(r.Host, func(r rune) bool { return r == ' ' || r == ',' }) {
+			c.Hostnames = appendUnique(c.Hostnames, strings.ToLower(h))
+		}
+		for _, h := range r.CoHosts {
+			c.CoHosts = appendUnique(c.CoHosts, strings.ToLower(h))
+		}
+		fillEmpty(&c.Org, r.Org)
+		fillEmpty(&c.Country, r.Country)
+		fillEmpty(&c.Region, r.Region)
+		fillEmpty(&c.City, r.City)
+		c.Sources = appendUnique(c.Sources, r.Source)
+		c.Strategies = appendUnique(c.Strategies, r.Strategy)
+		c.Queries = appendUnique(c.Queries, r.Query)
+		for _, s := range []string{r.FirstSeen, r.Seen} {
+			if t, ok := parseSeen(s); ok {
+				c.seen(t)
+			}
+		}
+		for field, value := range r.Extra {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+			if c.Extra == nil {
+				c.Extra = make(map[string][]string)
+			}
+			c.Extra[field] = appendUnique(c.Extra[field], value)
+		}
+	}
+	for i := range candidates {
+		sortPorts(candidates[i].Ports)
+	}
+	return candidates
+}
+
+func (c *Candidate) seen(t time.Time) {
+	if c.FirstSeen == nil || t.Before(*c.FirstSeen) {
+		c.FirstSeen = &t
+	}
+	if c.LastSeen == nil || t.After(*c.LastSeen) {
+		c.LastSeen = &t
+	}
+}
+
+// IsNetwork 判断候选是否为网段（如 SPF 中的 ip4 网段），网段无法直接验证
+func (c Candidate) IsNetwork() bool {
+	return strings.Contains(c.IP, "/")
+}
+
+// Location 以 country/region/city 的形式返回地理位置，省略空值
+func (c Candidate) Location() string {
+	var parts []string
+	for _, p := range []string{c.Country, c.Region, c.City} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// String 返回单行的文本形式，用于命令行输出与日志
+func (c Candidate) String() string {
+	parts := []string{displayIP(c.IP), fmt.Sprintf("score=%d", c.Score)}
+	if len(c.Ports) > 0 {
+		parts = append(parts, "ports="+strings.Join(c.Ports, ","))
+	}
+	if len(c.Hostnames) > 0 {
+		parts = append(parts, "hosts="+strings.Join(c.Hostnames, ","))
+	}
+	if c.Org != "" {
+		parts = append(parts, "org="+c.Org)
+	}
+	if loc := c.Location(); loc != "" {
+		parts = append(parts, "geo="+loc)
+	}
+	parts = append(parts, fmt.Sprintf("via=%s:%s", strings.Join(c.Sources, ","), strings.Join(c.Strategies, ",")))
+	if c.FirstSeen != nil {
+		parts = append(parts, fmt.Sprintf("seen=%s~%s", c.FirstSeen.Format("2006-01-02"), c.LastSeen.Format("2006-01-02")))
+	}
+	fields := make([]string, 0, len(c.Extra))
+	for field := range c.Extra {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		// cert 等字段包含换行，折叠为单行
+		value := strings.Join(strings.Fields(strings.Join(c.Extra[field], " | ")), " ")
+		parts = append(parts, field+"="+shorten(value, 80))
+	}
+	if v := c.Verification; v != nil {
+		parts = append(parts, fmt.Sprintf("verdict=%s(%d)", v.Verdict, v.Score))
+	}
+	if c.Certs != nil {
+		parts = append(parts, "tls="+c.certSummary())
+	}
+	return strings.Join(parts, "  ")
+}
+
+func fillEmpty(dst *string, src string) {
+	if *dst == "" {
+		*dst = src
+	}
+}
+
+// sortPorts 按数值升序排列端口
+func sortPorts(ports []string) {
+	sort.SliceStable(ports, func(i, j int) bool {
+		a, errA := strconv.Atoi(ports[i])
+		b, errB := strconv.Atoi(ports[j])
+		if errA != nil || errB != nil {
+			return ports[i] < ports[j]
+		}
+		return a < b
+	})
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"GoUnder/query"
 	"GoUnder/utils"
 	"encoding/base64"
 	"encoding/json"
@@ -10,8 +11,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
-	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -46,6 +46,11 @@ func (f *FofaResponse) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	// 错误响应不包含 results 字段
+	if len(aux.Results) == 0 || string(aux.Results) == "null" {
+		return nil
+	}
+
 	var result2D [][]string
 	if err := json.Unmarshal(aux.Results, &result2D); err == nil {
 		f.Results = result2D
@@ -63,43 +68,96 @@ func (f *FofaResponse) UnmarshalJSON(data []byte) error {
 	return fmt.Errorf("cannot unserialize results field: %s", string(aux.Results))
 }
 
-func cdnLookup(input string) [][]string {
-	var err error
-	fofaCfg, err = loadFofaConfig()
-	if err != nil {
-		log.Fatalf("Error loading fofa config: %v\n", err)
+func cdnLookup(input string) []Candidate {
+	if dryRun {
+		planned, err := planDryRun(input)
+		if err != nil {
+			log.Fatalf("Error loading search engines: %v\n", err)
+		}
+		printDryRun(planned)
+		return nil
 	}
-
-	patterns := []string{"host", "cert"} // default host + cert
-	if pattern != "" {
-		patterns = []string{pattern}
+	if detectFirst {
+		if _, proceed := detectBeforeLookup(input); !proceed {
+			return nil
+		}
 	}
 
-	resultSet := make([][]string, 0)
+	// 被动策略不经过搜索引擎，只有引擎策略需要加载引擎配置
+	var passive, plans []strategyPlan
+	for _, p := range cdnPatterns() {
+		if isPassive(p) {
+			passive = append(passive, strategyPlan{Pattern: p})
+			continue
+		}
+		plans = append(plans, strategyPlan{Pattern: p})
+	}
 
-	for _, p := range patterns {
-		queries, encoded := get_queries(p, input)
-		if queries != nil {
-			fmt.Printf("[+] Query string loaded: %s   %s\n", queries[0][:len(queries[0])-len(utils.FofaRules())], "+ <Fofa filter cdn Rules>...")
+	var active []SearchEngine
+	if len(plans) > 0 {
+		engines, err := newEngines(engineNames)
+		if err != nil {
+			log.Fatalf("Error loading search engines: %v\n", err)
 		}
-		for _, enc := range encoded {
-			for _, ip := range Query(enc, "ip,port,host,org,country,region,city") {
-				if len(ip) > 0 {
-					resultSet = append(resultSet, ip)
-				}
+		for _, e := range engines {
+			if err := e.LoadConfig(); err != nil {
+				fmt.Printf("⚠️  [%s] config not loaded, skipped: %v\n", e.Name(), err)
+				continue
 			}
+			active = append(active, e)
+		}
+		if len(active) == 0 {
+			fmt.Println("\n❌ No search engine available.")
+			return nil
 		}
+		titleLookup, lookupCost := fofaTitleLookup(active, plans, input)
+		for i := range plans {
+			plans[i].Terms = strategyValues(plans[i].Pattern, input, titleLookup)
+		}
+		var ok bool
+		if plans, ok = applyFofaBudget(active, plans, lookupCost); !ok {
+			return nil
+		}
+	}
+
+	var found []SearchResult
+	failed := 0
+	for _, plan := range passive {
+		results, n := runPassive(plan.Pattern, input)
+		found = append(found, results...)
+		failed += n
+	}
+	for _, plan := range plans {
+		for _, e := range active {
+			results, n := searchStrategy(e, plan.Pattern, plan.Terms)
+			found = append(found, results...)
+			failed += n
+		}
+	}
+	printCacheStats()
+	if failed > 0 {
+		fmt.Printf("\n⚠️  %d query(s) failed, results may be incomplete.\n", failed)
 	}
-	if len(resultSet) > 0 {
-		resultSet = unique2D(resultSet)
-		fmt.Println("\n✅ Promising target(s) found: ")
+	if len(found) > 0 {
+		candidates := groupCandidates(found)
+		if verifyFlag {
+			verifyCandidates(input, candidates)
+		}
+		if verifyTLS {
+			verifyCandidatesTLS(input, candidates)
+		}
+		lookupCoHosts(candidates)
+		scoreCandidates(input, candidates)
+		fmt.Printf("\n✅ %d promising target(s) found, sorted by score: \n", len(candidates))
 
 		var logContent strings.Builder
-		for _, ip := range resultSet {
-			line := strings.Join(ip, ", ")
+		for _, c := range candidates {
+			line := c.String()
+			reasons := "    ↳ " + strings.Join(c.Reasons, ", ")
 			fmt.Println("-", line)
+			fmt.Println(reasons)
 			if logFlag {
-				logContent.WriteString(line + "\n")
+				logContent.WriteString(line + "\n" + reasons + "\n")
 			}
 		}
 
@@ -109,13 +167,51 @@ func cdnLookup(input string) [][]string {
 		}
 		// -----------------------
 
-		return resultSet
+		return candidates
 	} else {
 		fmt.Println("\n❌ Could not find possible IP.")
 		return nil
 	}
 }
 
+// searchStrategy 使用单个引擎执行某个策略的全部查询，返回结果与失败的查询数
+func searchStrategy(e SearchEngine, p string, terms []strategyTerm) ([]SearchResult, int) {
+	var found []SearchResult
+	failed := 0
+	collect := func(q string, results []SearchResult, err error) {
+		if err != nil {
+			failed++
+			fmt.Printf("❌ [%s] search failed: %v\n", e.Name(), err)
+		}
+		for _, r := range results {
+			if r.IP != "" {
+				r.Strategy, r.Query = p, q
+				found = append(found, r)
+			}
+		}
+	}
+
+	// 支持证书指纹反查的引擎直接按证书查找主机
+	if cr, ok := e.(certResolver); ok && p == "cert" {
+		for _, t := range terms {
+			fmt.Printf("[+] [%s] Resolving certificates of %s into hosts...\n", e.Name(), t.Value)
+			results, err := cr.SearchCert(t.Value)
+			collect("cert pivot: "+t.Value, results, err)
+		}
+		return found, failed
+	}
+
+	queries := get_queries(e, terms)
+	if len(queries) > 0 {
+		fmt.Printf("[+] [%s] Query string loaded: %s   + <%s filter cdn Rules>...\n", e.Name(), firstTerm(e, terms), e.Name())
+	}
+	for _, q := range queries {
+		results, err := cachedSearch(e, q)
+		collect(q, results, err)
+	}
+	return found, failed
+}
+
 // 去重 [][]string
 func unique2D(input [][]string) [][]string {
 	seen := make(map[string]bool)
@@ -130,20 +226,26 @@ func unique2D(input [][]string) [][]string {
 	return result
 }
 
-func get_queries(p string, input string) ([]string, []string) {
-	var queries, encodedQueries []string
+// strategyTerm 是策略在本地计算出的一个查询取值，Pattern 决定引擎如何翻译它
+type strategyTerm struct {
+	Pattern string
+	Value   string
+}
+
+// strategyValues 执行策略所需的本地工作（提取 host、获取 title、计算 favicon hash），返回待查询的取值；
+// fofaTitles 为 false 时 title 策略不向 FOFA 查询标题
+func strategyValues(p string, input string, fofaTitles bool) []strategyTerm {
+	var terms []strategyTerm
 
 	switch p {
-	case "host":
-		q := fmt.Sprintf(`host="%s" `, extractHost(input)) + utils.FofaRules()
-		queries = append(queries, q)
+	case "host", "cert":
+		terms = append(terms, strategyTerm{p, extractHost(input)})
 
 	case "title":
-		titles, _ := get_titles(input)
+		titles, _ := get_titles(input, fofaTitles)
 		for _, title := range titles {
 			fmt.Println("[+] Get website title:", title)
-			q := fmt.Sprintf(`title="%s" `, title) + utils.FofaRules()
-			queries = append(queries, q)
+			terms = append(terms, strategyTerm{p, title})
 		}
 
 	case "icon":
@@ -152,30 +254,57 @@ func get_queries(p string, input string) ([]string, []string) {
 			fmt.Println("get icon_hash failed:", err)
 			break
 		}
-		fmt.Println("[+] Favicon hash loaded:", iconHash)
-		q := fmt.Sprintf(`icon_hash="%s" `, iconHash) + utils.FofaRules()
-		queries = append(queries, q)
-	case "cert":
-		q := fmt.Sprintf(`cert.subject.cn="%s" `, extractHost(input)) + utils.FofaRules()
-		queries = append(queries, q)
+		fmt.Println("[+] Favicon hash loaded:", iconHash.MMH3, "(md5:", iconHash.MD5+")")
+		// icon_md5 仅由支持 md5 图标哈希的引擎（ZoomEye）使用
+		terms = append(terms, strategyTerm{"icon", iconHash.MMH3}, strategyTerm{"icon_md5", iconHash.MD5})
 	}
+	return terms
+}
 
-	for _, q := range queries {
-		encodedQueries = append(encodedQueries, base64.StdEncoding.EncodeToString([]byte(q)))
+// get_queries 将策略取值翻译为指定引擎的完整查询语句，引擎不支持的取值被跳过
+func get_queries(e SearchEngine, terms []strategyTerm) []string {
+	var queries []string
+	for _, t := range terms {
+		if q := buildQuery(e, t.Pattern, t.Value); q != "" {
+			queries = append(queries, q)
+		}
 	}
-	return queries, encodedQueries
+	return queries
+}
+
+// firstTerm 返回第一个可被引擎翻译的查询条件，用于输出提示
+func firstTerm(e SearchEngine, terms []strategyTerm) string {
+	for _, t := range terms {
+		if q := e.Translate(t.Pattern, t.Value); q != "" {
+			return q
+		}
+	}
+	return ""
+}
+
+// titleLookupQuery 返回 title 策略查询网站标题所用的 FOFA 语句
+func titleLookupQuery(url string) string {
+	return query.Eq("host", extractHost(url)).String()
 }
 
-func get_titles(url string) ([]string, error) {
+func get_titles(url string, fofaLookup bool) ([]string, error) {
 	var titles []string
 	seen := make(map[string]bool)
 
 	// 构造 FOFA 查询
-	query := fmt.Sprintf(`host="%s"`, extractHost(url))
-	encodedQuery := base64.StdEncoding.EncodeToString([]byte(query))
-
-	// 调用 FOFA 查询 title 字段
-	results := Query(encodedQuery, "title")
+	q := titleLookupQuery(url)
+	encodedQuery := base64.StdEncoding.EncodeToString([]byte(q))
+
+	// 调用 FOFA 查询 title 字段（未启用 FOFA、超出预算或 dry-run 时跳过）
+	var results [][]string
+	if fofaLookup && fofaCfg != nil && !dryRun && !cacheGet("fofa", q, "title", &results) {
+		var err error
+		if results, err = Query(encodedQuery, "title"); err != nil {
+			fmt.Printf("⚠️  [fofa] title lookup failed: %v\n", err)
+		} else {
+			cachePut("fofa", q, "title", len(results), results)
+		}
+	}
 	for _, title := range results {
 		trimmed := strings.TrimSpace(strings.Join(title, ""))
 		if trimmed != "" && !seen[trimmed] {
@@ -302,7 +431,7 @@ func GetValidFaviconURL(pageURL string) (string, error) {
 
 	return "", fmt.Errorf("no valid favicon found")
 }
-func getFaviconHash(input string) (string, error) {
+func getFaviconHash(input string) (utils.IconHashes, error) {
 	// host := extractHost(input)
 	url := input
 	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
@@ -312,111 +441,193 @@ func getFaviconHash(input string) (string, error) {
 	// 下载 favicon
 	favURL, err := GetValidFaviconURL(url)
 	if err != nil {
-		return "", err
-	}
-	hash, err := utils.GetIconHashFromURL(favURL)
-	if err != nil {
-		return "", err
+		return utils.IconHashes{}, err
 	}
-	return fmt.Sprintf("%v", hash), nil // FOFA 使用的是有符号 int32
+	// mmh3 为 FOFA 使用的有符号 int32，md5 供 ZoomEye 使用
+	return utils.GetIconHashesFromURL(favURL)
 }
 
+// loadFofaConfig 加载 fofa.json，没有完整的账户时返回错误
 func loadFofaConfig() (*FofaConfig, error) {
-	configDir := "configs"
-	filename := "fofa.json"
-	path := filepath.Join(configDir, filename)
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			sysType := runtime.GOOS
-			switch sysType {
-			case "linux":
-				configDir = filepath.Join(os.Getenv("HOME"), ".config", "GoUnder")
-			case "windows":
-				configDir = filepath.Join(os.Getenv("APPDATA"), "GoUnder")
-			case "darwin":
-				configDir = filepath.Join(os.Getenv("HOME"), "Library", "Application Support", "GoUnder")
-			}
-			path := filepath.Join(configDir, filename)
-			data, err = os.ReadFile(path)
-			if err != nil {
-				if errors.Is(err, os.ErrNotExist) {
-					log.Printf("Config file not found: %s\n", path)
-					if err := os.MkdirAll(configDir, 0755); err != nil {
-						return nil, fmt.Errorf("creating config failed: %w", err)
-					}
-					// 默认配置
-					defaultCfg := FofaConfig{Email: "", Key: ""}
-					defaultData, _ := json.MarshalIndent(defaultCfg, "", "  ")
+	cfg := &FofaConfig{}
+	if err := loadConfigFile("fofa.json", cfg); err != nil {
+		return nil, err
+	}
+	accounts := cfg.accountList()
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("please complete the fofa config file with your email and API key")
+	}
+	fofaCfg = cfg
+	fofaPool = newFofaKeyPool(accounts)
+	if len(accounts) == 1 {
+		fmt.Printf("[+] Fofa account config loaded: %s\n", accounts[0].Email)
+	} else {
+		fmt.Printf("[+] Fofa account config loaded: %d accounts, starting with %s\n", len(accounts), fofaPool.Current().Email)
+	}
+	return cfg, nil
+}
 
-					// 写入文件
-					if err := os.WriteFile(path, defaultData, 0644); err != nil {
-						return nil, fmt.Errorf("writing config file failed: %w", err)
+func Query(encodedQuery string, fields ...string) ([][]string, error) {
+	return queryFofa(fofaBaseURL(), encodedQuery, fields...)
+}
 
-					}
-				}
-				log.Printf("Config file created: %s\n❗ Please complete the config file: %s", path, path)
-				return nil, err
-			}
-		}
-	}
-	err = json.Unmarshal(data, &fofaCfg)
-	if fofaCfg.Email == "" || fofaCfg.Key == "" {
-		log.Println("❗ Please complete the fofa config file with your email and API key.")
-		os.Exit(1)
+// fofaBaseURL 返回配置中的 FOFA 接口地址
+func fofaBaseURL() string {
+	if fofaCfg != nil && fofaCfg.BaseURL != "" {
+		return strings.TrimRight(fofaCfg.BaseURL, "/")
 	}
-	fmt.Printf("[+] Fofa account config loaded: %s\n", fofaCfg.Email)
-	return fofaCfg, err
+	return FofaDefaultBaseURL
 }
 
-func Query(encodedQuery string, fields ...string) [][]string {
-	client := resty.New()
-	var result FofaResponse
+// queryFofa 向指定地址的 FOFA API 发起查询，按 --size / --max-pages / --all 翻页；
+// 翻页中途失败时返回已获取的结果与错误
+func queryFofa(baseURL string, encodedQuery string, fields ...string) ([][]string, error) {
 	f := ""
 	if len(fields) > 0 {
 		f = fields[0]
 	}
+	size := fofaSize
+	if size < 1 || size > fofaMaxSize {
+		size = 100
+	}
 
-	_, err := client.R().
-		SetQueryParams(map[string]string{
-			"email":   fofaCfg.Email,
-			"key":     fofaCfg.Key,
+	results := make([][]string, 0)
+	total := 0
+	var queryErr error
+	// 仅在需要多页时尝试 search/next 游标接口，不可用时回退为 page 参数翻页
+	useNext := fofaAll || fofaMaxPages > 1
+	next := ""
+	page := 1
+	for ; fofaAll || page <= max(fofaMaxPages, 1); page++ {
+		account := fofaCredentials()
+		params := map[string]string{
+			"email":   account.Email,
+			"key":     account.Key,
 			"qbase64": encodedQuery,
-			"size":    "100",
+			"size":    strconv.Itoa(size),
 			"fields":  f,
-		}).
-		SetResult(&result).
-		Get("https://fofa.info/api/v1/search/all")
+		}
 
-	if err != nil {
-		fmt.Println("request FOFA API failed:", err)
-		fmt.Println(result)
-		return nil
-	}
+		var result FofaResponse
+		var err error
+		if useNext {
+			if next != "" {
+				params["next"] = next
+			}
+			result, err = fofaGet(baseURL+"/api/v1/search/next", params)
+			if page == 1 && fofaNextUnsupported(result, err) {
+				delete(params, "next")
+				useNext = false
+			}
+		}
+		if !useNext {
+			params["page"] = strconv.Itoa(page)
+			result, err = fofaGet(baseURL+"/api/v1/search/all", params)
+		}
 
-	if result.Error {
-		fmt.Printf("FOFA return error: %s\n", result.Msg)
-		return nil
+		if err != nil {
+			queryErr = fmt.Errorf("request FOFA API failed (page %d): %w", page, err)
+			break
+		}
+		if result.Error {
+			// 额度耗尽或重试后仍被限速时切换到下一个 key 重试当前页
+			quota := isFofaQuotaExhausted(result.Msg)
+			if (quota || isFofaRateLimited(result.Msg)) && fofaPool != nil && fofaPool.Rotate(result.Msg, quota) {
+				page--
+				continue
+			}
+			queryErr = fmt.Errorf("FOFA return error (page %d): %s", page, result.Msg)
+			break
+		}
+
+		if fofaPool != nil {
+			fofaPool.Record(len(result.Results))
+		}
+		if verbose {
+			fmt.Printf("[*] FOFA page %d served by key %s\n", page, account.Email)
+		}
+		total = result.Size
+		for _, entry := range result.Results {
+			if len(entry) > 0 && entry[0] != "" {
+				results = append(results, entry)
+			}
+		}
+
+		fetched := (page-1)*size + len(result.Results)
+		if len(result.Results) < size || fetched >= total {
+			break
+		}
+		if useNext {
+			if result.Next == "" {
+				break
+			}
+			next = result.Next
+		}
 	}
 
-	results := make([][]string, 0)
-	for _, entry := range result.Results {
-		if len(entry) > 0 && entry[0] != "" {
-			results = append(results, entry)
+	if total > 0 {
+		fmt.Printf("[+] FOFA returned size: %d, fetched: %d\n", total, len(results))
+		if !fofaAll && page > max(fofaMaxPages, 1) && len(results) < total {
+			fmt.Printf("⚠️  Page budget reached (%d page(s)), use --max-pages or --all to fetch the rest.\n", max(fofaMaxPages, 1))
 		}
 	}
-	return results
+	return results, queryErr
+}
+
+// errFofaEndpointUnsupported 表示接口地址不存在，常见于不提供 search/next 的私有部署
+var errFofaEndpointUnsupported = errors.New("endpoint unsupported")
+
+// fofaNextUnsupported 判断 search/next 是否不可用：接口不存在，或返回额度与限速以外的错误（如账户等级无权使用）；
+// 额度与限速错误仍交给 key 轮换处理
+func fofaNextUnsupported(result FofaResponse, err error) bool {
+	if err != nil {
+		return errors.Is(err, errFofaEndpointUnsupported)
+	}
+	return result.Error && !isFofaQuotaExhausted(result.Msg) && !isFofaRateLimited(result.Msg)
+}
 
-	// var unique []string
-	// for ip := range results {
-	// 	unique = append(unique, ip)
-	// }
-	// return unique
+// fofaGet 请求 FOFA 接口并解析返回结果，重试耗尽后仍失败的 HTTP 状态作为错误返回
+func fofaGet(url string, params map[string]string) (FofaResponse, error) {
+	var result FofaResponse
+	resp, err := apiClient("fofa").R().
+		SetQueryParams(params).
+		SetResult(&result).
+		Get(url)
+	if err != nil {
+		return result, err
+	}
+	if resp.StatusCode() == http.StatusNotFound || resp.StatusCode() == http.StatusMethodNotAllowed {
+		return result, fmt.Errorf("%w: HTTP %s", errFofaEndpointUnsupported, resp.Status())
+	}
+	if resp.IsError() {
+		return result, fmt.Errorf("HTTP %s", resp.Status())
+	}
+	return result, nil
 }
 
 func init() {
 	cdnCmd.Flags().StringVarP(&targetURL, "url", "u", "", "targetURL, eg: https://example.com")
-	cdnCmd.Flags().StringVarP(&pattern, "pattern", "p", "", "[host | title | icon | cert] (default: host + cert)")
+	cdnCmd.Flags().StringVarP(&pattern, "pattern", "p", "", "[host | title | icon | cert | history | ct | mail | subdomain | resolvers] (default: host + cert)")
+	cdnCmd.Flags().StringVarP(&engineNames, "engines", "", "fofa", "search engines, comma separated, eg: fofa,shodan,hunter")
+	cdnCmd.Flags().IntVarP(&fofaSize, "size", "", 100, "FOFA results per page (max 10000)")
+	cdnCmd.Flags().IntVarP(&fofaMaxPages, "max-pages", "", 1, "max FOFA pages fetched per query")
+	cdnCmd.Flags().BoolVarP(&fofaAll, "all", "", false, "fetch all FOFA results, ignore --max-pages")
+	cdnCmd.Flags().IntVarP(&fofaBudget, "budget", "", 0, "max FOFA result rows the planned queries may consume, 0 means unlimited")
+	cdnCmd.Flags().BoolVarP(&fofaBudgetTrim, "budget-trim", "", false, "drop strategies instead of aborting when --budget is exceeded")
+	cdnCmd.Flags().BoolVarP(&noCache, "no-cache", "", false, "do not read or write the local query cache")
+	cdnCmd.Flags().BoolVarP(&refreshCache, "refresh", "", false, "ignore cached results and refresh the cache")
+	cdnCmd.Flags().DurationVarP(&cacheTTL, "cache-ttl", "", 24*time.Hour, "reuse cached query results younger than this")
+	cdnCmd.Flags().StringVarP(&cdnExtraFields, "fields", "", "", "extra FOFA fields shown with each candidate, eg: title,server,cert,lastupdatetime")
+	cdnCmd.Flags().StringVarP(&ctBaseURL, "ct-url", "", CrtshDefaultBaseURL, "crt.sh compatible certificate transparency API used by -p ct")
+	cdnCmd.Flags().StringVarP(&subdomainWordlist, "wordlist", "", "", "extra subdomain prefixes for -p subdomain, one per line")
+	cdnCmd.Flags().IntVarP(&subdomainWorkers, "concurrency", "", resolveWorkers, "max concurrent DNS lookups for -p subdomain")
+	cdnCmd.Flags().StringVarP(&resolverList, "resolvers", "", "", "resolvers used by -p resolvers, comma separated ip[:port] or DoH JSON URL")
+	cdnCmd.Flags().BoolVarP(&verifyFlag, "verify", "", false, "replay requests with the target Host header against each candidate and score the responses")
+	cdnCmd.Flags().BoolVarP(&verifyTLS, "verify-tls", "", false, "connect to each candidate with the target as SNI and compare certificates")
+	cdnCmd.Flags().BoolVarP(&coHostLookup, "cohost-lookup", "", false, "query Shodan InternetDB for co-hosted names when scoring shared hosting (sends candidate IPs to a third party)")
+	cdnCmd.Flags().BoolVarP(&detectFirst, "detect", "", false, "detect the CDN first and skip targets that are not behind one")
+	cdnCmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, "only print the generated queries, send nothing")
+	cdnCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "verbose output, eg: which FOFA key served each query")
 	cdnCmd.Flags().BoolVarP(&logFlag, "log", "", true, "log the results")
 	rootCmd.AddCommand(cdnCmd)
 }
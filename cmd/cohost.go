@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const InternetDBDefaultBaseURL = "https://internetdb.shodan.io"
+
+// 最多为多少个候选查询同 IP 主机名
+const coHostLookupLimit = 50
+
+var (
+	// internetDBBaseURL 是 InternetDB 兼容接口的地址，按 IP 返回该地址上的全部主机名，无需 API key
+	internetDBBaseURL = InternetDBDefaultBaseURL
+	// coHostLookup 为 --cohost-lookup，向 InternetDB 查询同 IP 主机名；默认关闭，共享主机判断只使用引擎返回的数据
+	coHostLookup bool
+)
+
+type internetDBResponse struct {
+	Hostnames []string `json:"hostnames"`
+}
+
+// lookupCoHosts 在启用 --cohost-lookup 时为引擎未返回全量主机名的候选查询 InternetDB，
+// 补充同一 IP 上的其他主机名，供共享主机判断
+func lookupCoHosts(candidates []Candidate) {
+	if !coHostLookup {
+		return
+	}
+	looked, failed := 0, 0
+	for i := range candidates {
+		c := &candidates[i]
+		// InternetDB 只收录 IPv4 地址
+		if len(c.CoHosts) > 0 || c.IsNetwork() || net.ParseIP(c.IP).To4() == nil {
+			continue
+		}
+		if looked >= coHostLookupLimit {
+			break
+		}
+		looked++
+		names, err := internetDBHostnames(c.IP)
+		if err != nil {
+			failed++
+			continue
+		}
+		for _, name := range names {
+			c.CoHosts = appendUnique(c.CoHosts, strings.ToLower(name))
+		}
+	}
+	if looked > 0 {
+		fmt.Printf("[+] [internetdb] co-hosted names looked up for %d candidate(s), %d failed\n", looked, failed)
+	}
+}
+
+// internetDBHostnames 返回 InternetDB 记录的 ip 上的主机名，未收录时返回空
+func internetDBHostnames(ip string) ([]string, error) {
+	var result internetDBResponse
+	resp, err := apiClient("internetdb").
+		SetRetryCount(0).
+		R().
+		SetResult(&result).
+		Get(strings.TrimRight(internetDBBaseURL, "/") + "/" + ip)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("InternetDB return error: %s", resp.Status())
+	}
+	return result.Hostnames, nil
+}
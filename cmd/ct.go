@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"GoUnder/utils"
+)
+
+const CrtshDefaultBaseURL = "https://crt.sh"
+
+// ctBaseURL 是 crt.sh 兼容接口的地址，可由 --ct-url 覆盖
+var ctBaseURL = CrtshDefaultBaseURL
+
+type crtshEntry struct {
+	CommonName string `json:"common_name"`
+	NameValue  string `json:"name_value"`
+}
+
+// ctLookup 从证书透明度日志中收集目标注册域名下的全部 SAN，解析后排除 CDN 地址
+func ctLookup(host string) ([]SearchResult, error) {
+	domain := registeredDomain(host)
+	names, err := ctNames(domain)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[+] [ct] %d name(s) under %s found in certificate logs, resolving...\n", len(names), domain)
+
+	resolved := resolveHosts(names, resolveWorkers)
+	var results []SearchResult
+	behindCDN := 0
+	for _, name := range names {
+		ips, ok := resolved[name]
+		if !ok {
+			continue
+		}
+		for _, ip := range ips {
+			if utils.IsCDNIP(ip) {
+				behindCDN++
+				continue
+			}
+			results = append(results, SearchResult{IP: ip, Host: name, Source: "crt.sh"})
+		}
+	}
+	fmt.Printf("[+] [ct] %d name(s) resolved, %d address(es) inside CDN ranges dropped\n", len(resolved), behindCDN)
+	return results, nil
+}
+
+// ctNames 返回证书中出现的、属于 domain 的全部主机名，通配符证书去掉 *. 前缀
+func ctNames(domain string) ([]string, error) {
+	var entries []crtshEntry
+	resp, err := apiClient("crtsh").R().
+		SetQueryParams(map[string]string{
+			"q":      "%." + domain,
+			"output": "json",
+		}).
+		SetResult(&entries).
+		Get(strings.TrimRight(ctBaseURL, "/") + "/")
+	if err != nil {
+		return nil, fmt.Errorf("request crt.sh failed: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("crt.sh return error: %s", resp.Status())
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		for _, name := range strings.Fields(e.NameValue + "\n" + e.CommonName) {
+			name = strings.TrimPrefix(strings.ToLower(name), "*.")
+			if name == domain || strings.HasSuffix(name, "."+domain) {
+				seen[name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"GoUnder/utils"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// CNAME 链最多跟随的跳数
+	cnameMaxHops = 10
+	// 置信度达到该值视为目标经过 CDN
+	detectThreshold = 40
+)
+
+// 各类证据的得分，CNAME 与 IP 段几乎可以单独确定服务商，响应头与 Server 头可能被源站伪造或透传
+var detectWeights = map[string]int{
+	"cname":   45,
+	"ip":      45,
+	"header":  30,
+	"server":  20,
+	"generic": 10,
+}
+
+var (
+	// detectResolver 为跟随 CNAME 链时使用的 DNS 服务器
+	detectResolver = "8.8.8.8"
+	// detectFirst 为 cdn --detect，查询前先检测目标是否经过 CDN
+	detectFirst bool
+	detectJSON  bool
+)
+
+// DetectResult 是目标的 CDN 检测结果
+type DetectResult struct {
+	Host       string   `json:"host"`
+	CNAMEs     []string `json:"cnames"`
+	IPs        []string `json:"ips"`
+	URL        string   `json:"url,omitempty"`
+	Status     int      `json:"status,omitempty"`
+	Provider   string   `json:"provider,omitempty"`
+	Confidence int      `json:"confidence"`
+	Proxied    bool     `json:"proxied"`
+	Evidence   []string `json:"evidence"`
+	// Others 为同时命中的其他服务商及其置信度，常见于多 CDN 调度
+	Others map[string]int `json:"others,omitempty"`
+}
+
+var detectCmd = &cobra.Command{
+	Use:   "detect",
+	Short: "Detect whether the target is behind a CDN and which one.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if targetURL == "" {
+			fmt.Println("❗  use -u for target")
+			_ = cmd.Usage()
+			os.Exit(1)
+		}
+		d := detectCDN(targetURL)
+		if detectJSON {
+			out, _ := json.MarshalIndent(d, "", "  ")
+			fmt.Println(string(out))
+			return
+		}
+		printDetect(d)
+	},
+}
+
+// detectCDN 跟随 CNAME 链、比对解析地址与 CDN IP 段、检查响应头，综合给出服务商与置信度
+func detectCDN(input string) DetectResult {
+	host := extractHost(input)
+	// DNS 查询使用不带端口的主机名，端口只用于经 CDN 访问
+	name := stripPort(host)
+	d := DetectResult{Host: host}
+	scores := make(map[string]int)
+	add := func(provider, kind, detail string) {
+		scores[provider] += detectWeights[kind]
+		d.Evidence = append(d.Evidence, fmt.Sprintf("%s %s -> %s (+%d)", kind, detail, provider, detectWeights[kind]))
+	}
+
+	d.CNAMEs = cnameChain(name)
+	for _, name := range d.CNAMEs {
+		if p, suffix := utils.MatchCNAME(name); p != nil {
+			add(p.Name, "cname", name+" matches *."+suffix)
+		}
+	}
+
+	d.IPs = lookupIPs(name)
+	for _, ip := range d.IPs {
+		if provider := utils.CDNProviderOfIP(ip); provider != "" {
+			add(provider, "ip", ip+" in ranges")
+		}
+	}
+
+	generic := 0
+	if resp, url, err := fetchHeaders(host); err == nil {
+		d.URL, d.Status = url, resp.StatusCode
+		if p := utils.MatchServer(resp.Header.Get("Server")); p != nil {
+			add(p.Name, "server", resp.Header.Get("Server"))
+		}
+		names := make([]string, 0, len(resp.Header))
+		for name := range resp.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := resp.Header.Get(name)
+			if p := utils.MatchHeader(name, value); p != nil {
+				add(p.Name, "header", strings.ToLower(name)+": "+shorten(value, 40))
+				continue
+			}
+			for _, g := range utils.CDNGenericHeaders {
+				if strings.EqualFold(name, g) && generic < 2*detectWeights["generic"] {
+					generic += detectWeights["generic"]
+					d.Evidence = append(d.Evidence, fmt.Sprintf("generic %s: %s (+%d)", strings.ToLower(name), shorten(value, 40), detectWeights["generic"]))
+				}
+			}
+		}
+	} else {
+		d.Evidence = append(d.Evidence, "http request failed: "+shorten(err.Error(), 80))
+	}
+
+	for provider, score := range scores {
+		if score > d.Confidence || (score == d.Confidence && provider < d.Provider) {
+			d.Provider, d.Confidence = provider, score
+		}
+	}
+	for provider, score := range scores {
+		if provider != d.Provider {
+			if d.Others == nil {
+				d.Others = make(map[string]int)
+			}
+			d.Others[provider] = min(score, 100)
+		}
+	}
+	// 只有通用代理头时无法确定服务商
+	if d.Provider == "" && generic > 0 {
+		d.Provider = "unknown"
+	}
+	d.Confidence = min(d.Confidence+generic, 100)
+	d.Proxied = d.Confidence >= detectThreshold
+	return d
+}
+
+// detectBeforeLookup 检测并输出目标的 CDN 情况，目标确定未经过 CDN 时返回 false，无需继续查找源站
+func detectBeforeLookup(input string) (DetectResult, bool) {
+	d := detectCDN(input)
+	printDetect(d)
+	if !d.Proxied && !d.inconclusive() {
+		fmt.Println("\n⏭️  Target does not appear to be behind a CDN, origin lookup skipped.")
+		return d, false
+	}
+	return d, true
+}
+
+// inconclusive 判断检测是否因 DNS 与 HTTP 都失败而无法得出结论
+func (d DetectResult) inconclusive() bool {
+	return len(d.IPs) == 0 && len(d.CNAMEs) == 0 && d.Status == 0
+}
+
+func printDetect(d DetectResult) {
+	fmt.Printf("\n[+] CDN detection of %s\n", d.Host)
+	if len(d.CNAMEs) > 0 {
+		fmt.Printf("   CNAME chain : %s -> %s\n", stripPort(d.Host), strings.Join(d.CNAMEs, " -> "))
+	}
+	if len(d.IPs) > 0 {
+		fmt.Printf("   Addresses   : %s\n", strings.Join(d.IPs, ", "))
+	}
+	if d.URL != "" {
+		fmt.Printf("   Response    : %s %d\n", d.URL, d.Status)
+	}
+	for _, e := range d.Evidence {
+		fmt.Println("   -", e)
+	}
+
+	switch {
+	case d.inconclusive():
+		fmt.Println("\n⚠️  Target could not be resolved or fetched, detection inconclusive.")
+	case d.Proxied:
+		fmt.Printf("\n✅ Behind CDN: %s (confidence %d)\n", d.Provider, d.Confidence)
+	case d.Provider != "":
+		fmt.Printf("\n❔ Possibly behind a proxy: %s (confidence %d)\n", d.Provider, d.Confidence)
+	default:
+		fmt.Printf("\n❎ Not behind a known CDN (confidence %d)\n", d.Confidence)
+	}
+	if len(d.Others) > 0 {
+		others := make([]string, 0, len(d.Others))
+		for provider, score := range d.Others {
+			others = append(others, fmt.Sprintf("%s(%d)", provider, score))
+		}
+		sort.Strings(others)
+		fmt.Printf("   Also matched: %s\n", strings.Join(others, ", "))
+	}
+}
+
+// fetchHeaders 经 CDN 访问目标首页，优先 HTTPS，不跟随跳转
+func fetchHeaders(host string) (*http.Response, string, error) {
+	client := verifyClient("", "")
+	var lastErr error
+	for _, scheme := range []string{"https", "http"} {
+		url := scheme + "://" + urlHost(host) + "/"
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			return resp, url, nil
+		}
+		lastErr = err
+	}
+	return nil, "", lastErr
+}
+
+// cnameChain 逐跳查询 host 的 CNAME 记录，返回链上的全部别名（不含 host 本身）
+func cnameChain(host string) []string {
+	var chain []string
+	name := host
+	for i := 0; i < cnameMaxHops; i++ {
+		next, err := queryCNAME(detectResolver, name)
+		if err != nil || next == "" || strings.EqualFold(next, name) {
+			break
+		}
+		chain = append(chain, next)
+		name = next
+	}
+	if len(chain) > 0 {
+		return chain
+	}
+	// 指定的解析器不可用时回退到系统解析器，只能得到链的终点
+	if final := lookupCNAME(host); final != "" && !strings.EqualFold(final, host) {
+		chain = append(chain, final)
+	}
+	return chain
+}
+
+// queryCNAME 通过 server 查询 name 的 CNAME 记录，不存在时返回空字符串
+func queryCNAME(server, name string) (string, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	qname, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return "", err
+	}
+	var id [2]byte
+	_, _ = rand.Read(id[:])
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: binary.BigEndian.Uint16(id[:]), RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: qname, Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		return "", err
+	}
+
+	// UDP 失败或应答被截断（TC 位）时改用 TCP 重试
+	resp, err := exchangeDNS("udp", server, packed, msg.ID)
+	if err != nil || resp.Truncated {
+		resp, err = exchangeDNS("tcp", server, packed, msg.ID)
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, a := range resp.Answers {
+		if c, ok := a.Body.(*dnsmessage.CNAMEResource); ok && strings.EqualFold(a.Header.Name.String(), qname.String()) {
+			return strings.TrimSuffix(c.CNAME.String(), "."), nil
+		}
+	}
+	return "", nil
+}
+
+// exchangeDNS 通过 network（udp 或 tcp）向 server 发送查询报文并解析应答，TCP 报文带 2 字节长度前缀
+func exchangeDNS(network, server string, packed []byte, id uint16) (*dnsmessage.Message, error) {
+	conn, err := net.DialTimeout(network, server, resolveTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(resolveTimeout))
+
+	var buf []byte
+	if network == "tcp" {
+		framed := binary.BigEndian.AppendUint16(nil, uint16(len(packed)))
+		if _, err := conn.Write(append(framed, packed...)); err != nil {
+			return nil, err
+		}
+		var size [2]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return nil, err
+		}
+		buf = make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := conn.Write(packed); err != nil {
+			return nil, err
+		}
+		buf = make([]byte, 1232)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[:n]
+	}
+
+	var resp dnsmessage.Message
+	if err := resp.Unpack(buf); err != nil {
+		return nil, err
+	}
+	if resp.ID != id {
+		return nil, fmt.Errorf("mismatched DNS response id")
+	}
+	return &resp, nil
+}
+
+func init() {
+	detectCmd.Flags().StringVarP(&targetURL, "url", "u", "", "targetURL, eg: https://example.com")
+	detectCmd.Flags().StringVarP(&detectResolver, "resolver", "", detectResolver, "DNS server used to follow the CNAME chain, ip[:port]")
+	detectCmd.Flags().BoolVarP(&detectJSON, "json", "", false, "print the result as JSON")
+	rootCmd.AddCommand(detectCmd)
+}
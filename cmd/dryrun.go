@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"GoUnder/utils"
+	"encoding/base64"
+	"fmt"
+)
+
+// dryRunQuery 是 dry-run 模式下生成但未发送的一条查询
+type dryRunQuery struct {
+	Engine      string `json:"engine"`
+	Pattern     string `json:"pattern"`
+	Query       string `json:"query,omitempty"`
+	Bytes       int    `json:"bytes,omitempty"`
+	Base64      string `json:"base64,omitempty"`
+	Base64Bytes int    `json:"base64Bytes,omitempty"`
+	Note        string `json:"note,omitempty"`
+}
+
+// cdnPatterns 返回本次查询使用的策略，未指定 -p 时默认 host + cert
+func cdnPatterns() []string {
+	if pattern != "" {
+		return []string{pattern}
+	}
+	return []string{"host", "cert"}
+}
+
+// planDryRun 只执行本地工作（提取 host、获取 title、计算 favicon hash），返回各引擎将要发送的查询
+func planDryRun(input string) ([]dryRunQuery, error) {
+	engines, err := newEngines(engineNames)
+	if err != nil {
+		return nil, err
+	}
+
+	var planned []dryRunQuery
+	for _, p := range cdnPatterns() {
+		if isPassive(p) {
+			planned = append(planned, dryRunQuery{Engine: p, Pattern: p, Note: passiveStrategies[p].Describe(extractHost(input))})
+			continue
+		}
+		terms := strategyValues(p, input, false)
+		for _, e := range engines {
+			if _, ok := e.(certResolver); ok && p == "cert" {
+				for _, t := range terms {
+					planned = append(planned, dryRunQuery{
+						Engine:  e.Name(),
+						Pattern: p,
+						Note:    fmt.Sprintf("certificate search for %s + TLS fingerprint of %s, then host search by cert_sha256", t.Value, t.Value),
+					})
+				}
+				continue
+			}
+			for _, q := range get_queries(e, terms) {
+				dq := dryRunQuery{Engine: e.Name(), Pattern: p, Query: q, Bytes: len(q)}
+				if e.Name() == "fofa" {
+					dq.Base64 = base64.StdEncoding.EncodeToString([]byte(q))
+					dq.Base64Bytes = len(dq.Base64)
+				}
+				planned = append(planned, dq)
+			}
+		}
+	}
+	return planned, nil
+}
+
+// usesFofa 判断 dry-run 计划中是否包含 FOFA 查询
+func usesFofa(planned []dryRunQuery) bool {
+	for _, q := range planned {
+		if q.Engine == "fofa" && q.Query != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func printDryRun(planned []dryRunQuery) {
+	if len(planned) == 0 {
+		fmt.Println("\n❌ No query generated.")
+		return
+	}
+	fmt.Printf("\n🧪 Dry run, %d query(s) planned, nothing sent:\n", len(planned))
+	for _, q := range planned {
+		fmt.Printf("\n[%s] %s\n", q.Engine, q.Pattern)
+		if q.Note != "" {
+			fmt.Println("  ", q.Note)
+			continue
+		}
+		fmt.Printf("   query  (%d bytes): %s\n", q.Bytes, q.Query)
+		if q.Base64 != "" {
+			fmt.Printf("   base64 (%d bytes): %s\n", q.Base64Bytes, q.Base64)
+		}
+	}
+
+	if usesFofa(planned) {
+		fmt.Println("\n📦 FOFA CDN filter pieces:")
+		for _, piece := range utils.FofaRulePieces() {
+			filter := piece.Node.String()
+			fmt.Printf("   %-10s %-8s %4d rule(s) %6d bytes  %s\n", piece.Source, piece.Origin, piece.Rules(), len(filter), shorten(filter, 80))
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"GoUnder/query"
+)
+
+// SearchEngine 是空间测绘搜索引擎的统一接口，cdnLookup 通过它向多个引擎分发查询
+type SearchEngine interface {
+	// Name 返回引擎名称，与 --engines 参数中的名称一致
+	Name() string
+	// LoadConfig 加载引擎的认证配置
+	LoadConfig() error
+	// Translate 将策略及其取值翻译为引擎自身的查询语法，不支持的策略返回空字符串
+	Translate(p string, value string) string
+	// Filter 返回追加在查询后的 CDN 排除规则
+	Filter() string
+	// Search 执行查询并返回统一格式的结果
+	Search(query string) ([]SearchResult, error)
+}
+
+// certResolver 由支持证书指纹反查的引擎实现，cert 策略优先使用它代替普通查询
+type certResolver interface {
+	SearchCert(host string) ([]SearchResult, error)
+}
+
+// cacheKeyer 由结果受自身配置（如 max_pages）影响的引擎实现，返回的配置参与查询缓存的键
+type cacheKeyer interface {
+	CacheKey() string
+}
+
+// SearchResult 是各引擎归一化后的单条查询结果，由 groupCandidates 按 IP 聚合为 Candidate
+type SearchResult struct {
+	IP      string
+	Port    string
+	Host    string
+	Org     string
+	Country string
+	Region  string
+	City    string
+	Source  string
+	// Seen 为引擎记录的扫描时间，格式因引擎而异；FirstSeen 仅由提供时间范围的数据源（如被动 DNS）填写
+	Seen      string `json:",omitempty"`
+	FirstSeen string `json:",omitempty"`
+	// CoHosts 为引擎记录的该 IP 上的全部主机名，不受查询条件限制，用于判断共享主机
+	CoHosts []string `json:",omitempty"`
+	// Extra 为用户通过 --fields 额外请求的 FOFA 字段
+	Extra map[string]string `json:",omitempty"`
+	// Strategy 与 Query 记录结果由哪个策略的哪条查询得到
+	Strategy string `json:",omitempty"`
+	Query    string `json:",omitempty"`
+}
+
+// 已注册的搜索引擎，baseURL 为空时使用各引擎的官方地址
+var engineFactories = map[string]func(baseURL string) SearchEngine{
+	"censys":  func(baseURL string) SearchEngine { return newCensysEngine(baseURL) },
+	"fofa":    func(baseURL string) SearchEngine { return newFofaEngine(baseURL) },
+	"hunter":  func(baseURL string) SearchEngine { return newHunterEngine(baseURL) },
+	"quake":   func(baseURL string) SearchEngine { return newQuakeEngine(baseURL) },
+	"shodan":  func(baseURL string) SearchEngine { return newShodanEngine(baseURL) },
+	"zoomeye": func(baseURL string) SearchEngine { return newZoomEyeEngine(baseURL) },
+}
+
+// newEngines 解析逗号分隔的引擎列表
+func newEngines(names string) ([]SearchEngine, error) {
+	var engines []SearchEngine
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		factory, ok := engineFactories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown search engine: %s (available: %s)", name, strings.Join(engineNamesList(), ", "))
+		}
+		seen[name] = true
+		engines = append(engines, factory(""))
+	}
+	if len(engines) == 0 {
+		return nil, fmt.Errorf("no search engine specified")
+	}
+	return engines, nil
+}
+
+func engineNamesList() []string {
+	var names []string
+	for name := range engineFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// strategyFields 是策略取值对应的 FOFA 字段，icon_md5 与 cert_sha256 为 FOFA 不支持的扩展字段
+var strategyFields = map[string]string{
+	"host":        "host",
+	"title":       "title",
+	"icon":        "icon_hash",
+	"icon_md5":    "icon_md5",
+	"cert":        "cert.subject.cn",
+	"cert_sha256": "cert_sha256",
+}
+
+// translateStrategy 将策略取值构造成语法树并翻译为指定方言，方言不支持时返回空字符串
+func translateStrategy(d *query.Dialect, p string, value string) string {
+	field, ok := strategyFields[p]
+	if !ok {
+		return ""
+	}
+	q, err := query.Render(query.Eq(field, value), d)
+	if err != nil {
+		return ""
+	}
+	return q
+}
+
+// buildQuery 拼接策略查询与引擎的 CDN 排除规则
+func buildQuery(e SearchEngine, p string, value string) string {
+	term := e.Translate(p, value)
+	if term == "" {
+		return ""
+	}
+	filter := e.Filter()
+	if filter == "" {
+		return term
+	}
+	return term + " " + filter
+}
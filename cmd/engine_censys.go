@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"GoUnder/query"
+	"GoUnder/utils"
+)
+
+const CensysDefaultBaseURL = "https://search.censys.io"
+
+// Censys Search v2 单页最多返回 100 条结果
+const censysPageSize = 100
+
+type CensysConfig struct {
+	APIID    string `json:"api_id"`
+	Secret   string `json:"secret"`
+	BaseURL  string `json:"base_url,omitempty"`
+	MaxPages int    `json:"max_pages,omitempty"`
+	MaxCerts int    `json:"max_certs,omitempty"`
+}
+
+type censysLinks struct {
+	Next string `json:"next"`
+}
+
+type censysHostsResponse struct {
+	Result struct {
+		Total int          `json:"total"`
+		Hits  []censysHost `json:"hits"`
+		Links censysLinks  `json:"links"`
+	} `json:"result"`
+}
+
+type censysHost struct {
+	IP       string `json:"ip"`
+	Services []struct {
+		Port        int    `json:"port"`
+		ServiceName string `json:"service_name"`
+	} `json:"services"`
+	Location struct {
+		CountryCode string `json:"country_code"`
+		Province    string `json:"province"`
+		City        string `json:"city"`
+	} `json:"location"`
+	AutonomousSystem struct {
+		Name string `json:"name"`
+	} `json:"autonomous_system"`
+	DNS struct {
+		Names []string `json:"names"`
+	} `json:"dns"`
+	LastUpdatedAt string `json:"last_updated_at"`
+}
+
+type censysCertsResponse struct {
+	Result struct {
+		Total int `json:"total"`
+		Hits  []struct {
+			FingerprintSHA256 string   `json:"fingerprint_sha256"`
+			Names             []string `json:"names"`
+		} `json:"hits"`
+		Links censysLinks `json:"links"`
+	} `json:"result"`
+}
+
+type censysError struct {
+	Code   int    `json:"code"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+type censysEngine struct {
+	baseURL string
+	cfg     CensysConfig
+}
+
+func newCensysEngine(baseURL string) *censysEngine {
+	return &censysEngine{baseURL: baseURL}
+}
+
+func (e *censysEngine) Name() string {
+	return "censys"
+}
+
+func (e *censysEngine) LoadConfig() error {
+	cfg := CensysConfig{MaxPages: 1, MaxCerts: 10}
+	if err := loadConfigFile("censys.json", &cfg); err != nil {
+		return err
+	}
+	if cfg.APIID == "" || cfg.Secret == "" {
+		return fmt.Errorf("please complete the censys config file with your API ID and secret")
+	}
+	e.cfg = cfg
+	if e.baseURL == "" {
+		e.baseURL = cfg.BaseURL
+	}
+	fmt.Printf("[+] Censys account config loaded: %s\n", cfg.APIID)
+	return nil
+}
+
+func (e *censysEngine) Translate(p string, value string) string {
+	return translateStrategy(query.Censys, p, value)
+}
+
+// CloudFront 与 Cloudflare 的 IP 段由 IsCDNIP 在本地过滤
+func (e *censysEngine) Filter() string {
+	return `and not autonomous_system.name: "CLOUDFLARENET"`
+}
+
+func (e *censysEngine) CacheKey() string {
+	return fmt.Sprintf("pages=%d;certs=%d", e.cfg.MaxPages, e.cfg.MaxCerts)
+}
+
+func (e *censysEngine) Search(query string) ([]SearchResult, error) {
+	var results []SearchResult
+	cursor := ""
+	for page := 1; page <= max(e.cfg.MaxPages, 1); page++ {
+		var result censysHostsResponse
+		if err := e.get("/api/v2/hosts/search", query, cursor, &result); err != nil {
+			return results, err
+		}
+		for _, h := range result.Result.Hits {
+			if h.IP == "" || utils.IsCDNIP(h.IP) {
+				continue
+			}
+			host := ""
+			if len(h.DNS.Names) > 0 {
+				host = h.DNS.Names[0]
+			}
+			for _, s := range h.Services {
+				results = append(results, SearchResult{
+					IP:      h.IP,
+					Port:    strconv.Itoa(s.Port),
+					Host:    host,
+					CoHosts: h.DNS.Names,
+					Org:     h.AutonomousSystem.Name,
+					Country: h.Location.CountryCode,
+					Region:  h.Location.Province,
+					City:    h.Location.City,
+					Source:  e.Name(),
+					Seen:    h.LastUpdatedAt,
+				})
+			}
+		}
+		cursor = result.Result.Links.Next
+		if cursor == "" {
+			break
+		}
+	}
+	return results, nil
+}
+
+// SearchCert 将目标证书的 SHA-256 指纹反查为所有出示该证书的 IPv4 主机。
+// 指纹来源于证书库中包含该域名的证书，以及目标当前实际返回的证书
+func (e *censysEngine) SearchCert(host string) ([]SearchResult, error) {
+	// 目标当前返回的证书最可能仍部署在源站上，放在最前，不受 max_certs 截断影响
+	var fingerprints []string
+	if live, err := peerCertSHA256(host); err == nil {
+		fingerprints = append(fingerprints, live)
+	}
+	historical, err := e.certFingerprints(host)
+	if err != nil {
+		fmt.Printf("⚠️  [%s] certificate search failed: %v\n", e.Name(), err)
+	}
+	for _, fp := range historical {
+		fingerprints = appendUnique(fingerprints, fp)
+	}
+	if len(fingerprints) == 0 {
+		return nil, fmt.Errorf("no certificate found for %s", host)
+	}
+	if len(fingerprints) > e.cfg.MaxCerts && e.cfg.MaxCerts > 0 {
+		fingerprints = fingerprints[:e.cfg.MaxCerts]
+	}
+	fmt.Printf("[+] [%s] %d certificate fingerprint(s) loaded for %s\n", e.Name(), len(fingerprints), host)
+
+	var results []SearchResult
+	for _, fp := range fingerprints {
+		found, err := cachedSearch(e, buildQuery(e, "cert_sha256", fp))
+		if err != nil {
+			return results, err
+		}
+		results = append(results, found...)
+	}
+	return results, nil
+}
+
+// certFingerprints 在 Censys 证书库中查询包含 host 的证书指纹，结果与主机查询共用查询缓存
+func (e *censysEngine) certFingerprints(host string) ([]string, error) {
+	var fingerprints []string
+	q := "names: " + query.Quote(host)
+	// 证书库与主机库的查询语句可能相同，以 fields 区分
+	fields := cacheFields(e) + ";certificates"
+	if cacheGet(e.Name(), q, fields, &fingerprints) {
+		return fingerprints, nil
+	}
+	cursor := ""
+	for page := 1; page <= max(e.cfg.MaxPages, 1); page++ {
+		var result censysCertsResponse
+		if err := e.get("/api/v2/certificates/search", q, cursor, &result); err != nil {
+			return fingerprints, err
+		}
+		for _, hit := range result.Result.Hits {
+			if hit.FingerprintSHA256 != "" {
+				fingerprints = appendUnique(fingerprints, hit.FingerprintSHA256)
+			}
+		}
+		cursor = result.Result.Links.Next
+		if cursor == "" {
+			break
+		}
+	}
+	cachePut(e.Name(), q, fields, len(fingerprints), fingerprints)
+	return fingerprints, nil
+}
+
+func (e *censysEngine) get(path string, query string, cursor string, result interface{}) error {
+	var apiErr censysError
+	params := map[string]string{
+		"q":        query,
+		"per_page": strconv.Itoa(censysPageSize),
+	}
+	if cursor != "" {
+		params["cursor"] = cursor
+	}
+	resp, err := apiClient(e.Name()).R().
+		SetBasicAuth(e.cfg.APIID, e.cfg.Secret).
+		SetQueryParams(params).
+		SetResult(result).
+		SetError(&apiErr).
+		Get(e.endpoint() + path)
+	if err != nil {
+		return fmt.Errorf("request Censys API failed: %w", err)
+	}
+	if resp.IsError() {
+		msg := apiErr.Error
+		if msg == "" {
+			msg = resp.Status()
+		}
+		switch resp.StatusCode() {
+		case 401, 403:
+			return fmt.Errorf("Censys credentials rejected: %s", msg)
+		case 429:
+			return fmt.Errorf("Censys rate limit or quota exceeded: %s", msg)
+		}
+		return fmt.Errorf("Censys return error: %s", msg)
+	}
+	return nil
+}
+
+func (e *censysEngine) endpoint() string {
+	if e.baseURL == "" {
+		return CensysDefaultBaseURL
+	}
+	return strings.TrimRight(e.baseURL, "/")
+}
+
+// peerCertSHA256 连接 host:443 并返回其叶子证书的 SHA-256 指纹
+func peerCertSHA256(host string) (string, error) {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	} else {
+		host = net.JoinHostPort(host, "443")
+	}
+	cert, err := leafCert(host, hostname)
+	if err != nil {
+		return "", err
+	}
+	return certSHA256(cert), nil
+}
+
+// leafCert 以 SNI serverName 连接 addr 并返回对方出示的叶子证书，不校验证书有效性
+func leafCert(addr string, serverName string) (*x509.Certificate, error) {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate presented by %s", addr)
+	}
+	return certs[0], nil
+}
+
+func certSHA256(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"slices"
+	"strings"
+
+	"GoUnder/query"
+	"GoUnder/utils"
+)
+
+const FofaDefaultBaseURL = "https://fofa.info"
+
+// cdn 查询使用的 FOFA 返回字段
+const fofaCDNFields = "ip,port,host,org,country,region,city"
+
+type fofaEngine struct {
+	baseURL string
+}
+
+func newFofaEngine(baseURL string) *fofaEngine {
+	return &fofaEngine{baseURL: baseURL}
+}
+
+func (e *fofaEngine) Name() string {
+	return "fofa"
+}
+
+func (e *fofaEngine) LoadConfig() error {
+	cfg, err := loadFofaConfig()
+	if err != nil {
+		return err
+	}
+	fofaCfg = cfg
+	if e.baseURL == "" {
+		e.baseURL = cfg.BaseURL
+	}
+	return nil
+}
+
+func (e *fofaEngine) Translate(p string, value string) string {
+	return translateStrategy(query.FOFA, p, value)
+}
+
+func (e *fofaEngine) Filter() string {
+	return utils.FofaRules()
+}
+
+func (e *fofaEngine) Search(query string) ([]SearchResult, error) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(query))
+	extras := fofaExtraFields()
+	rows, err := queryFofa(e.endpoint(), encoded, fofaSearchFields())
+	var results []SearchResult
+	for _, row := range rows {
+		r := SearchResult{Source: e.Name()}
+		fields := []*string{&r.IP, &r.Port, &r.Host, &r.Org, &r.Country, &r.Region, &r.City}
+		for i := 0; i < len(row) && i < len(fields); i++ {
+			*fields[i] = row[i]
+		}
+		for i, field := range extras {
+			if len(fields)+i >= len(row) {
+				break
+			}
+			value := row[len(fields)+i]
+			if field == "lastupdatetime" {
+				r.Seen = value
+				continue
+			}
+			if r.Extra == nil {
+				r.Extra = make(map[string]string)
+			}
+			r.Extra[field] = value
+		}
+		results = append(results, r)
+	}
+	return results, err
+}
+
+// fofaExtraFields 返回 --fields 指定的额外 FOFA 字段，忽略已包含在基础字段中的字段
+func fofaExtraFields() []string {
+	base := strings.Split(fofaCDNFields, ",")
+	var extras []string
+	for _, f := range searchFieldList(cdnExtraFields) {
+		if !slices.Contains(base, f) {
+			extras = appendUnique(extras, f)
+		}
+	}
+	return extras
+}
+
+// fofaSearchFields 返回 cdn 查询请求的全部 FOFA 字段
+func fofaSearchFields() string {
+	return strings.Join(append([]string{fofaCDNFields}, fofaExtraFields()...), ",")
+}
+
+func (e *fofaEngine) endpoint() string {
+	if e.baseURL == "" {
+		return FofaDefaultBaseURL
+	}
+	return strings.TrimRight(e.baseURL, "/")
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"GoUnder/query"
+	"GoUnder/utils"
+)
+
+const HunterDefaultBaseURL = "https://hunter.qianxin.com"
+
+// Hunter 单页最多返回 100 条结果
+const hunterPageSize = 100
+
+type HunterConfig struct {
+	Key      string `json:"key"`
+	BaseURL  string `json:"base_url,omitempty"`
+	MaxPages int    `json:"max_pages,omitempty"`
+}
+
+type hunterResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Total     int    `json:"total"`
+		RestQuota string `json:"rest_quota"`
+		Arr       []struct {
+			IP       string `json:"ip"`
+			Port     int    `json:"port"`
+			Domain   string `json:"domain"`
+			Company  string `json:"company"`
+			ASOrg    string `json:"as_org"`
+			Country  string `json:"country"`
+			Province string `json:"province"`
+			City     string `json:"city"`
+			UpdateAt string `json:"updated_at"`
+		} `json:"arr"`
+	} `json:"data"`
+}
+
+type hunterEngine struct {
+	baseURL string
+	cfg     HunterConfig
+}
+
+func newHunterEngine(baseURL string) *hunterEngine {
+	return &hunterEngine{baseURL: baseURL}
+}
+
+func (e *hunterEngine) Name() string {
+	return "hunter"
+}
+
+func (e *hunterEngine) LoadConfig() error {
+	cfg := HunterConfig{MaxPages: 1}
+	if err := loadConfigFile("hunter.json", &cfg); err != nil {
+		return err
+	}
+	if cfg.Key == "" {
+		return fmt.Errorf("please complete the hunter config file with your API key")
+	}
+	e.cfg = cfg
+	if e.baseURL == "" {
+		e.baseURL = cfg.BaseURL
+	}
+	fmt.Printf("[+] Hunter account config loaded: %s***\n", cfg.Key[:min(5, len(cfg.Key))])
+	return nil
+}
+
+func (e *hunterEngine) Translate(p string, value string) string {
+	return translateStrategy(query.Hunter, p, value)
+}
+
+func (e *hunterEngine) Filter() string {
+	return utils.HunterRules()
+}
+
+func (e *hunterEngine) CacheKey() string {
+	return fmt.Sprintf("pages=%d", e.cfg.MaxPages)
+}
+
+func (e *hunterEngine) Search(query string) ([]SearchResult, error) {
+	client := apiClient(e.Name())
+	encoded := base64.URLEncoding.EncodeToString([]byte(query))
+
+	var results []SearchResult
+	fetched := 0
+	// 剩余积分每页都会返回，只在查询结束时输出最后一次
+	restQuota := ""
+	defer func() {
+		if restQuota != "" {
+			fmt.Printf("[+] [%s] %s\n", e.Name(), restQuota)
+		}
+	}()
+	for page := 1; page <= max(e.cfg.MaxPages, 1); page++ {
+		var result hunterResponse
+		resp, err := client.R().
+			SetQueryParams(map[string]string{
+				"api-key":   e.cfg.Key,
+				"search":    encoded,
+				"page":      strconv.Itoa(page),
+				"page_size": strconv.Itoa(hunterPageSize),
+				"is_web":    "3",
+			}).
+			SetResult(&result).
+			SetError(&result).
+			Get(e.endpoint() + "/openApi/search")
+		if err != nil {
+			return results, fmt.Errorf("request Hunter API failed: %w", err)
+		}
+		if resp.IsError() || result.Code != 200 {
+			return results, hunterErrorOf(result.Code, result.Message, resp.Status())
+		}
+
+		for _, a := range result.Data.Arr {
+			if a.IP == "" || utils.IsCDNIP(a.IP) {
+				continue
+			}
+			org := a.Company
+			if org == "" {
+				org = a.ASOrg
+			}
+			results = append(results, SearchResult{
+				IP:      a.IP,
+				Port:    strconv.Itoa(a.Port),
+				Host:    a.Domain,
+				Org:     org,
+				Country: a.Country,
+				Region:  a.Province,
+				City:    a.City,
+				Source:  e.Name(),
+				Seen:    a.UpdateAt,
+			})
+		}
+		restQuota = firstNonEmpty(result.Data.RestQuota, restQuota)
+
+		fetched += len(result.Data.Arr)
+		if len(result.Data.Arr) < hunterPageSize || fetched >= result.Data.Total {
+			break
+		}
+	}
+	return results, nil
+}
+
+func (e *hunterEngine) endpoint() string {
+	if e.baseURL == "" {
+		return HunterDefaultBaseURL
+	}
+	return strings.TrimRight(e.baseURL, "/")
+}
+
+func hunterErrorOf(code int, msg string, status string) error {
+	if msg == "" {
+		msg = status
+	}
+	switch {
+	case code == 401:
+		return fmt.Errorf("Hunter API key invalid: %s", msg)
+	case code == 429:
+		return fmt.Errorf("Hunter rate limit exceeded: %s", msg)
+	case isPointsError(msg):
+		return fmt.Errorf("Hunter points exhausted: %s", msg)
+	}
+	return fmt.Errorf("Hunter return error: [%d] %s", code, msg)
+}
+
+// isPointsError 判断国内测绘平台返回的错误是否为积分不足
+func isPointsError(msg string) bool {
+	return strings.Contains(msg, "积分") || strings.Contains(strings.ToLower(msg), "point")
+}
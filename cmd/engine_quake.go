@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"GoUnder/query"
+	"GoUnder/utils"
+)
+
+const QuakeDefaultBaseURL = "https://quake.360.net"
+
+// Quake 单页返回条数上限，每条结果消耗积分
+const quakePageSize = 100
+
+type QuakeConfig struct {
+	Key      string `json:"key"`
+	BaseURL  string `json:"base_url,omitempty"`
+	MaxPages int    `json:"max_pages,omitempty"`
+}
+
+type quakeResponse struct {
+	Code    json.RawMessage `json:"code"`
+	Message string          `json:"message"`
+	Data    []struct {
+		IP       string `json:"ip"`
+		Port     int    `json:"port"`
+		Hostname string `json:"hostname"`
+		Org      string `json:"org"`
+		Time     string `json:"time"`
+		Location struct {
+			CountryEn  string `json:"country_en"`
+			ProvinceEn string `json:"province_en"`
+			CityEn     string `json:"city_en"`
+		} `json:"location"`
+	} `json:"data"`
+	Meta struct {
+		Pagination struct {
+			Total int `json:"total"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+// ok 判断返回码是否为成功，Quake 的 code 可能是数字 0 或字符串错误码
+func (r quakeResponse) ok() bool {
+	code := strings.Trim(string(r.Code), `"`)
+	return code == "" || code == "0"
+}
+
+type quakeEngine struct {
+	baseURL string
+	cfg     QuakeConfig
+}
+
+func newQuakeEngine(baseURL string) *quakeEngine {
+	return &quakeEngine{baseURL: baseURL}
+}
+
+func (e *quakeEngine) Name() string {
+	return "quake"
+}
+
+func (e *quakeEngine) LoadConfig() error {
+	cfg := QuakeConfig{MaxPages: 1}
+	if err := loadConfigFile("quake.json", &cfg); err != nil {
+		return err
+	}
+	if cfg.Key == "" {
+		return fmt.Errorf("please complete the quake config file with your API key")
+	}
+	e.cfg = cfg
+	if e.baseURL == "" {
+		e.baseURL = cfg.BaseURL
+	}
+	fmt.Printf("[+] Quake account config loaded: %s***\n", cfg.Key[:min(5, len(cfg.Key))])
+	return nil
+}
+
+func (e *quakeEngine) Translate(p string, value string) string {
+	return translateStrategy(query.Quake, p, value)
+}
+
+func (e *quakeEngine) Filter() string {
+	return utils.QuakeRules()
+}
+
+func (e *quakeEngine) CacheKey() string {
+	return fmt.Sprintf("pages=%d", e.cfg.MaxPages)
+}
+
+func (e *quakeEngine) Search(query string) ([]SearchResult, error) {
+	client := apiClient(e.Name())
+
+	var results []SearchResult
+	fetched := 0
+	for page := 0; page < max(e.cfg.MaxPages, 1); page++ {
+		var result quakeResponse
+		resp, err := client.R().
+			SetHeader("X-QuakeToken", e.cfg.Key).
+			SetHeader("Content-Type", "application/json").
+			SetBody(map[string]interface{}{
+				"query":   query,
+				"start":   page * quakePageSize,
+				"size":    quakePageSize,
+				"include": []string{"ip", "port", "hostname", "org", "location", "time"},
+			}).
+			SetResult(&result).
+			SetError(&result).
+			Post(e.endpoint() + "/api/v3/search/quake_service")
+		if err != nil {
+			return results, fmt.Errorf("request Quake API failed: %w", err)
+		}
+		if resp.IsError() || !result.ok() {
+			return results, quakeErrorOf(resp.StatusCode(), string(result.Code), result.Message, resp.Status())
+		}
+
+		for _, d := range result.Data {
+			if d.IP == "" || utils.IsCDNIP(d.IP) {
+				continue
+			}
+			results = append(results, SearchResult{
+				IP:      d.IP,
+				Port:    strconv.Itoa(d.Port),
+				Host:    d.Hostname,
+				Org:     d.Org,
+				Country: d.Location.CountryEn,
+				Region:  d.Location.ProvinceEn,
+				City:    d.Location.CityEn,
+				Source:  e.Name(),
+				Seen:    d.Time,
+			})
+		}
+
+		fetched += len(result.Data)
+		if len(result.Data) < quakePageSize || fetched >= result.Meta.Pagination.Total {
+			break
+		}
+	}
+	return results, nil
+}
+
+func (e *quakeEngine) endpoint() string {
+	if e.baseURL == "" {
+		return QuakeDefaultBaseURL
+	}
+	return strings.TrimRight(e.baseURL, "/")
+}
+
+func quakeErrorOf(status int, code string, msg string, statusText string) error {
+	if msg == "" {
+		msg = statusText
+	}
+	switch {
+	case status == 401 || status == 403:
+		return fmt.Errorf("Quake API key invalid: %s", msg)
+	case status == 429:
+		return fmt.Errorf("Quake rate limit exceeded: %s", msg)
+	case isPointsError(msg):
+		return fmt.Errorf("Quake points exhausted: %s", msg)
+	}
+	return fmt.Errorf("Quake return error: [%s] %s", strings.Trim(code, `"`), msg)
+}
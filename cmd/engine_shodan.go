@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"GoUnder/query"
+	"GoUnder/utils"
+)
+
+const ShodanDefaultBaseURL = "https://api.shodan.io"
+
+// Shodan 每页固定返回 100 条结果，翻页（第 2 页起）每页消耗 1 个 query credit
+const shodanPageSize = 100
+
+type ShodanConfig struct {
+	Key      string `json:"key"`
+	BaseURL  string `json:"base_url,omitempty"`
+	MaxPages int    `json:"max_pages,omitempty"`
+}
+
+type shodanAPIInfo struct {
+	QueryCredits int    `json:"query_credits"`
+	Plan         string `json:"plan"`
+}
+
+type shodanResponse struct {
+	Total   int           `json:"total"`
+	Matches []shodanMatch `json:"matches"`
+}
+
+type shodanMatch struct {
+	IPStr     string   `json:"ip_str"`
+	Port      int      `json:"port"`
+	Hostnames []string `json:"hostnames"`
+	Org       string   `json:"org"`
+	Timestamp string   `json:"timestamp"`
+	Location  struct {
+		CountryCode string `json:"country_code"`
+		RegionCode  string `json:"region_code"`
+		City        string `json:"city"`
+	} `json:"location"`
+}
+
+type shodanError struct {
+	Error string `json:"error"`
+}
+
+type shodanEngine struct {
+	baseURL string
+	cfg     ShodanConfig
+}
+
+func newShodanEngine(baseURL string) *shodanEngine {
+	return &shodanEngine{baseURL: baseURL}
+}
+
+func (e *shodanEngine) Name() string {
+	return "shodan"
+}
+
+func (e *shodanEngine) LoadConfig() error {
+	cfg := ShodanConfig{MaxPages: 1}
+	if err := loadConfigFile("shodan.json", &cfg); err != nil {
+		return err
+	}
+	if cfg.Key == "" {
+		return fmt.Errorf("please complete the shodan config file with your API key")
+	}
+	e.cfg = cfg
+	if e.baseURL == "" {
+		e.baseURL = cfg.BaseURL
+	}
+	fmt.Printf("[+] Shodan account config loaded: %s***\n", cfg.Key[:min(5, len(cfg.Key))])
+	return nil
+}
+
+func (e *shodanEngine) Translate(p string, value string) string {
+	return translateStrategy(query.Shodan, p, value)
+}
+
+func (e *shodanEngine) Filter() string {
+	return utils.ShodanRules()
+}
+
+func (e *shodanEngine) CacheKey() string {
+	return fmt.Sprintf("pages=%d", e.cfg.MaxPages)
+}
+
+func (e *shodanEngine) Search(query string) ([]SearchResult, error) {
+	client := apiClient(e.Name())
+
+	// 先确认剩余 query credits，翻页数不超过可用额度
+	var info shodanAPIInfo
+	var apiErr shodanError
+	resp, err := client.R().
+		SetQueryParam("key", e.cfg.Key).
+		SetResult(&info).
+		SetError(&apiErr).
+		Get(e.endpoint() + "/api-info")
+	if err != nil {
+		return nil, fmt.Errorf("request Shodan API failed: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("Shodan return error: %s", shodanErrorMsg(resp.Status(), apiErr))
+	}
+	if info.QueryCredits < 1 {
+		return nil, fmt.Errorf("Shodan query credits exhausted (plan: %s)", info.Plan)
+	}
+
+	maxPages := e.cfg.MaxPages
+	if maxPages < 1 {
+		maxPages = 1
+	}
+	if maxPages > info.QueryCredits {
+		maxPages = info.QueryCredits
+	}
+
+	var results []SearchResult
+	fetched := 0
+	for page := 1; page <= maxPages; page++ {
+		var result shodanResponse
+		apiErr = shodanError{}
+		resp, err := client.R().
+			SetQueryParams(map[string]string{
+				"key":    e.cfg.Key,
+				"query":  query,
+				"page":   strconv.Itoa(page),
+				"minify": "true",
+			}).
+			SetResult(&result).
+			SetError(&apiErr).
+			Get(e.endpoint() + "/shodan/host/search")
+		if err != nil {
+			return results, fmt.Errorf("request Shodan API failed: %w", err)
+		}
+		if resp.IsError() {
+			return results, fmt.Errorf("Shodan return error: %s", shodanErrorMsg(resp.Status(), apiErr))
+		}
+
+		for _, m := range result.Matches {
+			// 本地排除落在 CloudFront / Cloudflare IP 段内的结果
+			if m.IPStr == "" || utils.IsCDNIP(m.IPStr) {
+				continue
+			}
+			results = append(results, SearchResult{
+				IP:      m.IPStr,
+				Port:    strconv.Itoa(m.Port),
+				Host:    strings.Join(m.Hostnames, " "),
+				CoHosts: m.Hostnames,
+				Org:     m.Org,
+				Country: m.Location.CountryCode,
+				Region:  m.Location.RegionCode,
+				City:    m.Location.City,
+				Source:  e.Name(),
+				Seen:    m.Timestamp,
+			})
+		}
+
+		fetched += len(result.Matches)
+		if len(result.Matches) < shodanPageSize || fetched >= result.Total {
+			break
+		}
+	}
+	return results, nil
+}
+
+func (e *shodanEngine) endpoint() string {
+	if e.baseURL == "" {
+		return ShodanDefaultBaseURL
+	}
+	return strings.TrimRight(e.baseURL, "/")
+}
+
+func shodanErrorMsg(status string, apiErr shodanError) string {
+	if apiErr.Error != "" {
+		return apiErr.Error
+	}
+	return status
+}
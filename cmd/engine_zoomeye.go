@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"GoUnder/query"
+	"GoUnder/utils"
+)
+
+const ZoomEyeDefaultBaseURL = "https://api.zoomeye.org"
+
+// ZoomEye host/search 每页固定返回 20 条结果
+const zoomeyePageSize = 20
+
+type ZoomEyeConfig struct {
+	Key      string `json:"key"`
+	BaseURL  string `json:"base_url,omitempty"`
+	MaxPages int    `json:"max_pages,omitempty"`
+}
+
+type zoomeyeResponse struct {
+	Total     int            `json:"total"`
+	Available int            `json:"available"`
+	Matches   []zoomeyeMatch `json:"matches"`
+}
+
+type zoomeyeName struct {
+	Names struct {
+		En string `json:"en"`
+	} `json:"names"`
+}
+
+type zoomeyeMatch struct {
+	IP       string `json:"ip"`
+	PortInfo struct {
+		Port     int    `json:"port"`
+		Hostname string `json:"hostname"`
+	} `json:"portinfo"`
+	GeoInfo struct {
+		Country      zoomeyeName `json:"country"`
+		Subdivisions zoomeyeName `json:"subdivisions"`
+		City         zoomeyeName `json:"city"`
+		Organization string      `json:"organization"`
+		ISP          string      `json:"isp"`
+	} `json:"geoinfo"`
+	Timestamp string `json:"timestamp"`
+}
+
+type zoomeyeError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+type zoomeyeEngine struct {
+	baseURL string
+	cfg     ZoomEyeConfig
+}
+
+func newZoomEyeEngine(baseURL string) *zoomeyeEngine {
+	return &zoomeyeEngine{baseURL: baseURL}
+}
+
+func (e *zoomeyeEngine) Name() string {
+	return "zoomeye"
+}
+
+func (e *zoomeyeEngine) LoadConfig() error {
+	cfg := ZoomEyeConfig{MaxPages: 1}
+	if err := loadConfigFile("zoomeye.json", &cfg); err != nil {
+		return err
+	}
+	if cfg.Key == "" {
+		return fmt.Errorf("please complete the zoomeye config file with your API key")
+	}
+	e.cfg = cfg
+	if e.baseURL == "" {
+		e.baseURL = cfg.BaseURL
+	}
+	fmt.Printf("[+] ZoomEye account config loaded: %s***\n", cfg.Key[:min(5, len(cfg.Key))])
+	return nil
+}
+
+func (e *zoomeyeEngine) Translate(p string, value string) string {
+	return translateStrategy(query.ZoomEye, p, value)
+}
+
+// ZoomEye 的 dork 不支持按 Server 头批量排除，CDN 结果由 IsCDNIP 在本地过滤
+func (e *zoomeyeEngine) Filter() string {
+	return ""
+}
+
+func (e *zoomeyeEngine) CacheKey() string {
+	return fmt.Sprintf("pages=%d", e.cfg.MaxPages)
+}
+
+func (e *zoomeyeEngine) Search(query string) ([]SearchResult, error) {
+	client := apiClient(e.Name())
+
+	maxPages := e.cfg.MaxPages
+	if maxPages < 1 {
+		maxPages = 1
+	}
+
+	var results []SearchResult
+	fetched := 0
+	for page := 1; page <= maxPages; page++ {
+		var result zoomeyeResponse
+		var apiErr zoomeyeError
+		resp, err := client.R().
+			SetHeader("API-KEY", e.cfg.Key).
+			SetQueryParams(map[string]string{
+				"query": query,
+				"page":  strconv.Itoa(page),
+			}).
+			SetResult(&result).
+			SetError(&apiErr).
+			Get(e.endpoint() + "/host/search")
+		if err != nil {
+			return results, fmt.Errorf("request ZoomEye API failed: %w", err)
+		}
+		if resp.IsError() {
+			return results, zoomeyeErrorOf(resp.StatusCode(), resp.Status(), apiErr)
+		}
+
+		for _, m := range result.Matches {
+			if m.IP == "" || utils.IsCDNIP(m.IP) {
+				continue
+			}
+			org := m.GeoInfo.Organization
+			if org == "" {
+				org = m.GeoInfo.ISP
+			}
+			results = append(results, SearchResult{
+				IP:      m.IP,
+				Port:    strconv.Itoa(m.PortInfo.Port),
+				Host:    m.PortInfo.Hostname,
+				Org:     org,
+				Country: m.GeoInfo.Country.Names.En,
+				Region:  m.GeoInfo.Subdivisions.Names.En,
+				City:    m.GeoInfo.City.Names.En,
+				Source:  e.Name(),
+				Seen:    m.Timestamp,
+			})
+		}
+
+		fetched += len(result.Matches)
+		if len(result.Matches) < zoomeyePageSize || fetched >= result.Total {
+			break
+		}
+	}
+	return results, nil
+}
+
+func (e *zoomeyeEngine) endpoint() string {
+	if e.baseURL == "" {
+		return ZoomEyeDefaultBaseURL
+	}
+	return strings.TrimRight(e.baseURL, "/")
+}
+
+func zoomeyeErrorOf(code int, status string, apiErr zoomeyeError) error {
+	msg := apiErr.Message
+	if msg == "" {
+		msg = status
+	}
+	switch {
+	case code == 402 || apiErr.Error == "credits_insufficent" || apiErr.Error == "credits_insufficient":
+		return fmt.Errorf("ZoomEye quota exhausted: %s", msg)
+	case code == 401:
+		return fmt.Errorf("ZoomEye API key invalid: %s", msg)
+	}
+	return fmt.Errorf("ZoomEye return error: %s", msg)
+}
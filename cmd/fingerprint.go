@@ -12,7 +12,6 @@ import (
 	"runtime"
 	"strings"
 
-	"github.com/go-resty/resty/v2"
 	wappalyzer "github.com/projectdiscovery/wappalyzergo"
 	"github.com/spf13/cobra"
 )
@@ -88,28 +87,16 @@ func whatcmdAnalyze(url string) []map[string]string {
 	outcome := []map[string]string{}
 	whatcmsCfg, err := loadWhatcmsConfig()
 	if err != nil {
-		log.Fatalf("error loading whatcms config.\n")
+		fmt.Printf("❌ error loading whatcms config: %v\n", err)
+		return nil
 	}
 	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
 		url = "http://" + url
 	}
-	client := resty.New()
-
-	resp, err := client.R().SetHeader("Accept", "application/json").
-		SetQueryParams(map[string]string{"key": whatcmsCfg.Key, "url": url}).
-		Get("https://whatcms.org/API/Tech")
-
+	results, err := whatcmsTech(whatcmsCfg.Key, url)
 	if err != nil {
-		log.Fatalf("request whatcms failed: %v", err)
-	}
-	var result map[string]interface{}
-	if err := json.Unmarshal(resp.Body(), &result); err != nil {
-		log.Fatalf("parsing JSON format failed: %v", err)
-	}
-	// 提取 results 字段
-	results, ok := result["results"].([]interface{})
-	if !ok {
-		log.Fatalf("results field is not type of []interface{}")
+		fmt.Printf("❌ %v\n", err)
+		return nil
 	}
 	// 遍历 results 并输出格式化信息
 	if len(results) > 0 {
@@ -155,6 +142,30 @@ func whatcmdAnalyze(url string) []map[string]string {
 	}
 
 }
+
+// whatcmsTech 调用 whatcms 接口并返回 results 字段
+func whatcmsTech(key string, url string) ([]interface{}, error) {
+	resp, err := apiClient("whatcms").R().SetHeader("Accept", "application/json").
+		SetQueryParams(map[string]string{"key": key, "url": url}).
+		Get("https://whatcms.org/API/Tech")
+	if err != nil {
+		return nil, fmt.Errorf("request whatcms failed: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("request whatcms failed: HTTP %s", resp.Status())
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return nil, fmt.Errorf("parsing JSON format failed: %w", err)
+	}
+	// 提取 results 字段
+	results, ok := result["results"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("results field is not type of []interface{}")
+	}
+	return results, nil
+}
+
 func loadWhatcmsConfig() (*WhatcmsConfig, error) {
 	// configDir := "configs"
 	// fileName := "whatcms.json"
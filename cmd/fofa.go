@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// FofaInfo 是 /api/v1/info/my 返回的账户信息
+type FofaInfo struct {
+	Error           bool   `json:"error"`
+	Msg             string `json:"errmsg"`
+	Email           string `json:"email"`
+	Username        string `json:"username"`
+	IsVIP           bool   `json:"isvip"`
+	VIPLevel        int    `json:"vip_level"`
+	FCoin           int    `json:"fcoin"`
+	FofaPoint       int    `json:"fofa_point"`
+	RemainFreePoint int    `json:"remain_free_point"`
+	RemainAPIQuery  int    `json:"remain_api_query"`
+	RemainAPIData   int    `json:"remain_api_data"`
+}
+
+var fofaCmd = &cobra.Command{
+	Use:   "fofa",
+	Short: "FOFA account utilities.",
+}
+
+var fofaInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show FOFA VIP level, remaining quota and F-point balance.",
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		fofaCfg, err = loadFofaConfig()
+		if err != nil {
+			log.Fatalf("Error loading fofa config: %v\n", err)
+		}
+		info, err := fofaAccountInfo()
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		fmt.Println("\n✅ FOFA account info:")
+		fmt.Printf("- Username          : %s\n", info.Username)
+		fmt.Printf("- Email             : %s\n", info.Email)
+		fmt.Printf("- VIP               : %v (level %d)\n", info.IsVIP, info.VIPLevel)
+		fmt.Printf("- Remain API query  : %d\n", info.RemainAPIQuery)
+		fmt.Printf("- Remain API data   : %d\n", info.RemainAPIData)
+		fmt.Printf("- F-point balance   : %d\n", info.FofaPoint)
+		fmt.Printf("- Free points       : %d\n", info.RemainFreePoint)
+		fmt.Printf("- F-coin            : %d\n", info.FCoin)
+	},
+}
+
+// fofaAccountInfo 查询当前 FOFA 账户的会员等级与剩余额度
+func fofaAccountInfo() (FofaInfo, error) {
+	var info FofaInfo
+	account := fofaCredentials()
+	resp, err := apiClient("fofa").R().
+		SetQueryParams(map[string]string{
+			"email": account.Email,
+			"key":   account.Key,
+		}).
+		SetResult(&info).
+		Get(fofaBaseURL() + "/api/v1/info/my")
+	if err != nil {
+		return info, fmt.Errorf("request FOFA API failed: %w", err)
+	}
+	if resp.IsError() {
+		return info, fmt.Errorf("request FOFA API failed: HTTP %s", resp.Status())
+	}
+	if info.Error {
+		return info, fmt.Errorf("FOFA return error: %s", info.Msg)
+	}
+	return info, nil
+}
+
+// strategyPlan 是某个策略在发送查询前计算好的取值
+type strategyPlan struct {
+	Pattern string
+	Terms   []strategyTerm
+}
+
+// fofaQueryCost 返回一次 FOFA 查询最多消耗的数据条数
+func fofaQueryCost() int {
+	size := fofaSize
+	if size < 1 || size > fofaMaxSize {
+		size = 100
+	}
+	return size * max(fofaMaxPages, 1)
+}
+
+// fofaBudgeted 判断 --budget 是否作用于本次查询
+func fofaBudgeted(engines []SearchEngine) bool {
+	if fofaBudget <= 0 {
+		return false
+	}
+	for _, e := range engines {
+		if e.Name() == "fofa" {
+			return true
+		}
+	}
+	return false
+}
+
+// fofaTitleLookup 决定 title 策略是否向 FOFA 查询网站标题，并返回计入 --budget 的消耗。
+// 该查询需在预算检查之前发送以得到待查询的标题，单独即超出预算时跳过，只使用本地抓取的标题
+func fofaTitleLookup(engines []SearchEngine, plans []strategyPlan, input string) (bool, int) {
+	if fofaCfg == nil {
+		return false, 0
+	}
+	hasTitle := false
+	for _, plan := range plans {
+		hasTitle = hasTitle || plan.Pattern == "title"
+	}
+	if !hasTitle || !fofaBudgeted(engines) {
+		return hasTitle, 0
+	}
+	// --all 的消耗无法预估，applyFofaBudget 随后会中止
+	if fofaAll || cacheFresh("fofa", titleLookupQuery(input), "title") {
+		return !fofaAll, 0
+	}
+	cost := fofaQueryCost()
+	if cost > fofaBudget {
+		fmt.Printf("⚠️  FOFA title lookup (cost %d) exceeds budget %d, skipped.\n", cost, fofaBudget)
+		return false, 0
+	}
+	return true, cost
+}
+
+// applyFofaBudget 估算计划中 FOFA 查询消耗的数据条数（命中本地缓存的查询不计），超出 --budget 时中止，
+// 或在 --budget-trim 下从后往前丢弃策略直到满足预算；lookupCost 为已发送的 title 查询的消耗
+func applyFofaBudget(engines []SearchEngine, plans []strategyPlan, lookupCost int) ([]strategyPlan, bool) {
+	var fofa SearchEngine
+	for _, e := range engines {
+		if e.Name() == "fofa" {
+			fofa = e
+		}
+	}
+	if fofa == nil || fofaBudget <= 0 {
+		return plans, true
+	}
+	if fofaAll {
+		fmt.Println("❗ --budget cannot be combined with --all, the number of pages is unknown before querying.")
+		return nil, false
+	}
+
+	perQuery := fofaQueryCost()
+	costs := make([]int, len(plans))
+	total := lookupCost
+	if lookupCost > 0 {
+		fmt.Printf("[+] FOFA cost estimate for title lookup: 1 query(s) x %d = %d\n", perQuery, lookupCost)
+	}
+	fields := cacheFields(fofa)
+	for i, plan := range plans {
+		queries := get_queries(fofa, plan.Terms)
+		cached := 0
+		for _, q := range queries {
+			if cacheFresh(fofa.Name(), q, fields) {
+				cached++
+			}
+		}
+		costs[i] = (len(queries) - cached) * perQuery
+		total += costs[i]
+		fmt.Printf("[+] FOFA cost estimate for %s: %d query(s) x %d = %d", plan.Pattern, len(queries)-cached, perQuery, costs[i])
+		if cached > 0 {
+			fmt.Printf(" (%d cached)", cached)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("[+] FOFA budget: estimated %d / %d result rows\n", total, fofaBudget)
+
+	if total == 0 {
+		return plans, true
+	}
+	if info, err := fofaAccountInfo(); err == nil && total > info.RemainAPIData {
+		fmt.Printf("⚠️  Estimated cost exceeds remaining API data (%d), the rest will be charged in F-points (balance: %d).\n", info.RemainAPIData, info.FofaPoint)
+	}
+
+	if total <= fofaBudget {
+		return plans, true
+	}
+	if !fofaBudgetTrim {
+		fmt.Printf("❌ Estimated FOFA cost %d exceeds budget %d, aborted. Use --budget-trim to drop strategies.\n", total, fofaBudget)
+		return nil, false
+	}
+	for len(plans) > 0 && total > fofaBudget {
+		last := len(plans) - 1
+		fmt.Printf("⚠️  Strategy %s dropped to fit the budget (cost %d).\n", plans[last].Pattern, costs[last])
+		total -= costs[last]
+		plans, costs = plans[:last], costs[:last]
+	}
+	if len(plans) == 0 {
+		fmt.Printf("❌ No strategy fits the FOFA budget %d, aborted.\n", fofaBudget)
+		return nil, false
+	}
+	return plans, true
+}
+
+func init() {
+	fofaCmd.AddCommand(fofaInfoCmd)
+	rootCmd.AddCommand(fofaCmd)
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"GoUnder/utils"
+)
+
+const fofaKeyStateFile = "fofa_keys_state.json"
+
+// FOFA 额度或余额耗尽时返回的错误码，命中时该 key 当天不再使用；
+// 限速错误码见 fofaRateLimitCodes，重试仍失败时只在本次运行中切换 key
+var fofaQuotaCodes = []string{"[820031]", "[820032]", "[820033]", "[820006]"}
+
+// fofaKeyUsage 是单个 key 在本地记录的使用情况
+type fofaKeyUsage struct {
+	Requests    int       `json:"requests"`
+	Rows        int       `json:"rows"`
+	LastUsed    time.Time `json:"last_used"`
+	ExhaustedAt time.Time `json:"exhausted_at,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// fofaKeyPool 在多个 FOFA 账户间轮换，并把使用情况保存到配置目录
+type fofaKeyPool struct {
+	accounts []FofaAccount
+	current  int
+	failures int
+	state    map[string]*fofaKeyUsage
+	path     string
+}
+
+// UnmarshalJSON 兼容单账户对象、带 accounts 列表的对象以及账户数组三种写法
+func (c *FofaConfig) UnmarshalJSON(data []byte) error {
+	var list []FofaAccount
+	if err := json.Unmarshal(data, &list); err == nil {
+		*c = FofaConfig{Accounts: list}
+		return nil
+	}
+	type Alias FofaConfig
+	return json.Unmarshal(data, (*Alias)(c))
+}
+
+// accountList 合并顶层凭据与 accounts 列表，忽略不完整与重复的条目
+func (c *FofaConfig) accountList() []FofaAccount {
+	var accounts []FofaAccount
+	seen := make(map[string]bool)
+	for _, a := range append([]FofaAccount{{Email: c.Email, Key: c.Key}}, c.Accounts...) {
+		if a.Email == "" || a.Key == "" || seen[a.Key] {
+			continue
+		}
+		seen[a.Key] = true
+		accounts = append(accounts, a)
+	}
+	return accounts
+}
+
+func newFofaKeyPool(accounts []FofaAccount) *fofaKeyPool {
+	p := &fofaKeyPool{accounts: accounts, state: make(map[string]*fofaKeyUsage)}
+	if path, err := utils.CacheFilePath(fofaKeyStateFile); err == nil {
+		p.path = path
+		if data, err := os.ReadFile(path); err == nil {
+			_ = json.Unmarshal(data, &p.state)
+		}
+	}
+	// 跳过今天已经耗尽的 key
+	for i, a := range accounts {
+		if !p.exhaustedToday(a.Email) {
+			p.current = i
+			break
+		}
+	}
+	return p
+}
+
+// exhaustedToday 判断 key 今天是否已因额度耗尽被记录
+func (p *fofaKeyPool) exhaustedToday(email string) bool {
+	u := p.state[email]
+	return u != nil && u.ExhaustedAt.Format("2006-01-02") == time.Now().Format("2006-01-02")
+}
+
+// Current 返回当前使用的账户
+func (p *fofaKeyPool) Current() FofaAccount {
+	return p.accounts[p.current]
+}
+
+// Rotate 切换到下一个今天未耗尽的 key，exhausted 为 true 时将当前 key 记录为当天耗尽，
+// 限速只在本次运行中切换，所有 key 都失败时返回 false
+func (p *fofaKeyPool) Rotate(reason string, exhausted bool) bool {
+	u := p.usage(p.Current().Email)
+	if exhausted {
+		u.ExhaustedAt = time.Now()
+	}
+	u.LastError = reason
+	p.save()
+
+	prev := p.Current().Email
+	for p.failures++; p.failures < len(p.accounts); p.failures++ {
+		p.current = (p.current + 1) % len(p.accounts)
+		if !p.exhaustedToday(p.Current().Email) {
+			fmt.Printf("⚠️  FOFA key %s unavailable (%s), rotating to %s\n", prev, reason, p.Current().Email)
+			return true
+		}
+	}
+	fmt.Printf("⚠️  All %d FOFA key(s) exhausted or rate limited.\n", len(p.accounts))
+	return false
+}
+
+// Record 记录当前 key 成功完成一次请求
+func (p *fofaKeyPool) Record(rows int) {
+	p.failures = 0
+	u := p.usage(p.Current().Email)
+	u.Requests++
+	u.Rows += rows
+	u.LastUsed = time.Now()
+	u.LastError = ""
+	p.save()
+}
+
+func (p *fofaKeyPool) usage(email string) *fofaKeyUsage {
+	u, ok := p.state[email]
+	if !ok {
+		u = &fofaKeyUsage{}
+		p.state[email] = u
+	}
+	return u
+}
+
+func (p *fofaKeyPool) save() {
+	if p.path == "" {
+		return
+	}
+	data, _ := json.MarshalIndent(p.state, "", "  ")
+	_ = os.WriteFile(p.path, data, 0644)
+}
+
+// fofaCredentials 返回当前应使用的 FOFA 凭据
+func fofaCredentials() FofaAccount {
+	if fofaPool != nil {
+		return fofaPool.Current()
+	}
+	if fofaCfg != nil {
+		return FofaAccount{Email: fofaCfg.Email, Key: fofaCfg.Key}
+	}
+	return FofaAccount{}
+}
+
+// isFofaQuotaExhausted 判断 FOFA 错误是否为额度或余额耗尽
+func isFofaQuotaExhausted(msg string) bool {
+	for _, code := range fofaQuotaCodes {
+		if strings.Contains(msg, code) {
+			return true
+		}
+	}
+	return false
+}
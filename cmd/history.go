@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"GoUnder/utils"
+)
+
+const (
+	SecurityTrailsDefaultBaseURL = "https://api.securitytrails.com"
+	VirusTotalDefaultBaseURL     = "https://www.virustotal.com"
+)
+
+// HistoryConfig 是 history 策略使用的被动 DNS 数据源，未填写的数据源被跳过
+type HistoryConfig struct {
+	SecurityTrailsKey     string `json:"securitytrails_key"`
+	SecurityTrailsBaseURL string `json:"securitytrails_base_url,omitempty"`
+	VirusTotalKey         string `json:"virustotal_key"`
+	VirusTotalBaseURL     string `json:"virustotal_base_url,omitempty"`
+	// GenericURL 为自定义 JSON 接口，{host} 会被替换为目标主机名
+	GenericURL     string            `json:"generic_url"`
+	GenericHeaders map[string]string `json:"generic_headers,omitempty"`
+	MaxPages       int               `json:"max_pages,omitempty"`
+}
+
+type securityTrailsResponse struct {
+	Pages   int `json:"pages"`
+	Records []struct {
+		Values []struct {
+			IP   string `json:"ip"`
+			IPv6 string `json:"ipv6"`
+		} `json:"values"`
+		FirstSeen     string   `json:"first_seen"`
+		LastSeen      string   `json:"last_seen"`
+		Organizations []string `json:"organizations"`
+	} `json:"records"`
+	Message string `json:"message"`
+}
+
+type virusTotalResponse struct {
+	Data []struct {
+		Attributes struct {
+			IPAddress string `json:"ip_address"`
+			Date      int64  `json:"date"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Links struct {
+		Next string `json:"next"`
+	} `json:"links"`
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// genericHistoryRecord 是自定义接口返回的一条记录，接口可直接返回记录数组，或将其放在 records / data 字段中
+type genericHistoryRecord struct {
+	IP        string `json:"ip"`
+	FirstSeen string `json:"first_seen"`
+	LastSeen  string `json:"last_seen"`
+}
+
+// historyLookup 依次查询已配置的被动 DNS 数据源，返回目标主机历史 A / AAAA 记录中不属于 CDN 的 IP
+func historyLookup(host string) ([]SearchResult, error) {
+	cfg := HistoryConfig{MaxPages: 1}
+	if err := loadConfigFile("history.json", &cfg); err != nil {
+		return nil, err
+	}
+
+	providers := []struct {
+		name    string
+		enabled bool
+		lookup  func(HistoryConfig, string) ([]SearchResult, error)
+	}{
+		{"securitytrails", cfg.SecurityTrailsKey != "", securityTrailsHistory},
+		{"virustotal", cfg.VirusTotalKey != "", virusTotalHistory},
+		{"generic", cfg.GenericURL != "", genericHistory},
+	}
+
+	var results []SearchResult
+	var errs []error
+	enabled := 0
+	for _, p := range providers {
+		if !p.enabled {
+			continue
+		}
+		enabled++
+		found, err := p.lookup(cfg, host)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.name, err))
+		}
+		kept := 0
+		for _, r := range found {
+			if r.IP == "" || utils.IsCDNIP(r.IP) {
+				continue
+			}
+			r.Source, r.Host = p.name, host
+			results = append(results, r)
+			kept++
+		}
+		fmt.Printf("[+] [history] %s: %d record(s), %d outside CDN ranges\n", p.name, len(found), kept)
+	}
+	if enabled == 0 {
+		return nil, fmt.Errorf("please complete the history config file with at least one passive DNS source")
+	}
+	return results, errors.Join(errs...)
+}
+
+func securityTrailsHistory(cfg HistoryConfig, host string) ([]SearchResult, error) {
+	baseURL := strings.TrimRight(cfg.SecurityTrailsBaseURL, "/")
+	if baseURL == "" {
+		baseURL = SecurityTrailsDefaultBaseURL
+	}
+	client := apiClient("securitytrails")
+
+	var results []SearchResult
+	for _, recordType := range []string{"a", "aaaa"} {
+		for page := 1; page <= max(cfg.MaxPages, 1); page++ {
+			var result securityTrailsResponse
+			resp, err := client.R().
+				SetHeader("APIKEY", cfg.SecurityTrailsKey).
+				SetQueryParam("page", strconv.Itoa(page)).
+				SetResult(&result).
+				SetError(&result).
+				Get(baseURL + "/v1/history/" + host + "/dns/" + recordType)
+			if err != nil {
+				return results, fmt.Errorf("request SecurityTrails API failed: %w", err)
+			}
+			if resp.IsError() {
+				return results, fmt.Errorf("SecurityTrails return error: %s", firstNonEmpty(result.Message, resp.Status()))
+			}
+			for _, rec := range result.Records {
+				org := ""
+				if len(rec.Organizations) > 0 {
+					org = rec.Organizations[0]
+				}
+				for _, v := range rec.Values {
+					results = append(results, SearchResult{IP: firstNonEmpty(v.IP, v.IPv6), Org: org, FirstSeen: rec.FirstSeen, Seen: rec.LastSeen})
+				}
+			}
+			if page >= result.Pages {
+				break
+			}
+		}
+	}
+	return results, nil
+}
+
+func virusTotalHistory(cfg HistoryConfig, host string) ([]SearchResult, error) {
+	baseURL := strings.TrimRight(cfg.VirusTotalBaseURL, "/")
+	if baseURL == "" {
+		baseURL = VirusTotalDefaultBaseURL
+	}
+	client := apiClient("virustotal")
+
+	var results []SearchResult
+	next := baseURL + "/api/v3/domains/" + host + "/resolutions?limit=40"
+	for page := 1; page <= max(cfg.MaxPages, 1) && next != ""; page++ {
+		var result virusTotalResponse
+		resp, err := client.R().
+			SetHeader("x-apikey", cfg.VirusTotalKey).
+			SetResult(&result).
+			SetError(&result).
+			Get(next)
+		if err != nil {
+			return results, fmt.Errorf("request VirusTotal API failed: %w", err)
+		}
+		if resp.IsError() {
+			return results, fmt.Errorf("VirusTotal return error: %s", firstNonEmpty(result.Error.Message, resp.Status()))
+		}
+		// VirusTotal 只给出解析时间，首次与最近时间相同，由 Candidate 聚合出范围
+		for _, d := range result.Data {
+			seen := ""
+			if d.Attributes.Date > 0 {
+				seen = time.Unix(d.Attributes.Date, 0).UTC().Format(time.RFC3339)
+			}
+			results = append(results, SearchResult{IP: d.Attributes.IPAddress, FirstSeen: seen, Seen: seen})
+		}
+		next = result.Links.Next
+	}
+	return results, nil
+}
+
+func genericHistory(cfg HistoryConfig, host string) ([]SearchResult, error) {
+	resp, err := apiClient("history").R().
+		SetHeaders(cfg.GenericHeaders).
+		Get(strings.ReplaceAll(cfg.GenericURL, "{host}", host))
+	if err != nil {
+		return nil, fmt.Errorf("request history endpoint failed: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("history endpoint return error: %s", resp.Status())
+	}
+
+	var records []genericHistoryRecord
+	if err := json.Unmarshal(resp.Body(), &records); err != nil {
+		var wrapped struct {
+			Records []genericHistoryRecord `json:"records"`
+			Data    []genericHistoryRecord `json:"data"`
+		}
+		if err := json.Unmarshal(resp.Body(), &wrapped); err != nil {
+			return nil, fmt.Errorf("cannot parse history endpoint response: %w", err)
+		}
+		records = append(wrapped.Records, wrapped.Data...)
+	}
+
+	var results []SearchResult
+	for _, rec := range records {
+		results = append(results, SearchResult{IP: rec.IP, FirstSeen: rec.FirstSeen, Seen: rec.LastSeen})
+	}
+	return results, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"GoUnder/utils"
+)
+
+// SPF 规范限制一次检查中 include / redirect / a / mx 等机制最多触发 10 次 DNS 查询，整个展开过程共用该上限
+const spfMaxLookups = 10
+
+// 常见邮件服务商的域名后缀，命中的地址属于服务商而非目标源站
+var mailProviders = []struct {
+	Name     string
+	Suffixes []string
+}{
+	{"Google Workspace", []string{"google.com", "googlemail.com", "_spf.google.com"}},
+	{"Microsoft 365", []string{"outlook.com", "protection.outlook.com", "office365.com"}},
+	{"Amazon SES", []string{"amazonses.com"}},
+	{"SendGrid", []string{"sendgrid.net"}},
+	{"Mailgun", []string{"mailgun.org", "mailgun.net"}},
+	{"Mailchimp", []string{"mcsv.net", "mandrillapp.com", "mailchimp.com"}},
+	{"Zoho", []string{"zoho.com", "zoho.eu", "zohomail.com"}},
+	{"Tencent Exmail", []string{"qq.com", "exmail.qq.com"}},
+	{"NetEase", []string{"163.com", "126.com", "netease.com", "ym.163.com"}},
+	{"Alibaba Mail", []string{"mxhichina.com", "aliyun.com", "alibaba.com"}},
+	{"Yandex", []string{"yandex.net", "yandex.ru"}},
+	{"Proton", []string{"protonmail.ch", "proton.me"}},
+	{"Mimecast", []string{"mimecast.com"}},
+	{"Proofpoint", []string{"pphosted.com", "ppe-hosted.com"}},
+	{"Broadcom MessageLabs", []string{"messagelabs.com"}},
+	{"SparkPost", []string{"sparkpostmail.com"}},
+	{"Zendesk", []string{"zendesk.com"}},
+	{"Salesforce", []string{"salesforce.com", "exacttarget.com"}},
+}
+
+// mailProvider 返回主机名所属的邮件服务商，不属于已知服务商时返回空字符串
+func mailProvider(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, p := range mailProviders {
+		for _, suffix := range p.Suffixes {
+			if host == suffix || strings.HasSuffix(host, "."+suffix) {
+				return p.Name
+			}
+		}
+	}
+	return ""
+}
+
+// mailLookup 解析目标注册域名的 MX、SPF（递归展开 include / redirect / a / mx / ip4 / ip6）与 DMARC 记录，
+// 返回其中不属于 CDN 的地址，属于常见邮件服务商的地址会被标记
+func mailLookup(host string) ([]SearchResult, error) {
+	domain := registeredDomain(host)
+	m := &mailCollector{domain: domain, seen: make(map[string]bool)}
+
+	mxs, err := lookupMX(domain)
+	if err != nil {
+		fmt.Printf("⚠️  [mail] MX lookup of %s failed: %v\n", domain, err)
+	}
+	for _, mx := range mxs {
+		fmt.Printf("[+] [mail] MX %s\n", mx)
+		m.addHost("mx", mx, "mx "+mx, mailProvider(mx))
+	}
+
+	m.expandSPF(domain, "spf "+domain, "")
+
+	txts, _ := lookupTXT("_dmarc." + domain)
+	for _, txt := range txts {
+		if !strings.HasPrefix(strings.ToLower(txt), "v=dmarc1") {
+			continue
+		}
+		fmt.Printf("[+] [mail] DMARC %s\n", txt)
+		// 汇总报告发往目标自己域名下的邮箱时，收件主机通常也是自建的
+		for _, addr := range dmarcAddresses(txt) {
+			if registeredDomain(addr) == domain {
+				m.addHost("dmarc", addr, "dmarc "+addr, mailProvider(addr))
+			}
+		}
+	}
+
+	if txts, err := lookupTXT(domain); err == nil {
+		for _, txt := range txts {
+			if !strings.HasPrefix(strings.ToLower(txt), "v=spf1") {
+				fmt.Printf("[+] [mail] TXT %s\n", shorten(txt, 100))
+			}
+		}
+	}
+
+	if len(mxs) == 0 && len(m.results) == 0 && err != nil {
+		return nil, err
+	}
+	return m.results, nil
+}
+
+type mailCollector struct {
+	domain  string
+	seen    map[string]bool
+	results []SearchResult
+	// spfLookups 为展开 SPF 时已触发的 DNS 查询次数
+	spfLookups int
+}
+
+// spfLookup 占用一次 SPF DNS 查询额度，超过 spfMaxLookups 时返回 false
+func (m *mailCollector) spfLookup(term string) bool {
+	m.spfLookups++
+	if m.spfLookups == spfMaxLookups+1 {
+		fmt.Printf("⚠️  [mail] SPF lookup limit (%d) reached, %s and later terms skipped\n", spfMaxLookups, term)
+	}
+	return m.spfLookups <= spfMaxLookups
+}
+
+// add 记录 host 对应的一个地址或网段，CDN 地址被丢弃
+func (m *mailCollector) add(source, host, ip, via, provider string) {
+	if m.seen[ip] {
+		return
+	}
+	m.seen[ip] = true
+	if !strings.Contains(ip, "/") && utils.IsCDNIP(ip) {
+		return
+	}
+	r := SearchResult{IP: ip, Host: host, Source: source, Extra: map[string]string{"mail": via}}
+	if provider != "" {
+		r.Extra["mail_provider"] = provider
+	}
+	m.results = append(m.results, r)
+}
+
+// addHost 解析主机名并记录其全部地址
+func (m *mailCollector) addHost(source, host, via, provider string) {
+	for _, ip := range lookupIPs(host) {
+		m.add(source, host, ip, via, provider)
+	}
+}
+
+// expandSPF 递归展开 name 的 SPF 记录，provider 为上层 include 所属的邮件服务商
+func (m *mailCollector) expandSPF(name, via, provider string) {
+	txts, err := lookupTXT(name)
+	if err != nil {
+		return
+	}
+	for _, txt := range txts {
+		if !strings.HasPrefix(strings.ToLower(txt), "v=spf1") {
+			continue
+		}
+		fmt.Printf("[+] [mail] SPF %s: %s\n", name, txt)
+		for _, term := range strings.Fields(txt)[1:] {
+			// 限定符 + - ~ ? 不影响地址本身
+			term = strings.TrimLeft(term, "+-~?")
+			mech, value, _ := strings.Cut(term, ":")
+			if mech == term {
+				mech, value, _ = strings.Cut(term, "=")
+			}
+			cidr := value
+			// a/24、mx:example.com/24 等前缀长度只影响匹配范围
+			mech, _, _ = strings.Cut(strings.ToLower(mech), "/")
+			value, _, _ = strings.Cut(value, "/")
+			switch mech {
+			case "include", "redirect", "a", "mx":
+				if !m.spfLookup(term) {
+					continue
+				}
+			}
+			switch mech {
+			case "include", "redirect":
+				p := provider
+				if p == "" {
+					p = mailProvider(value)
+				}
+				m.expandSPF(value, via+" > "+value, p)
+			case "ip4", "ip6":
+				// 保留网段前缀，/32 与 /128 视为单个地址
+				single := map[string]string{"ip4": "/32", "ip6": "/128"}[mech]
+				if cidr != "" {
+					m.add("spf", name, normalizeIP(strings.TrimSuffix(cidr, single)), via, provider)
+				}
+			case "a":
+				target := firstNonEmpty(value, name)
+				m.addHost("spf", target, via+" > a:"+target, firstNonEmpty(provider, mailProvider(target)))
+			case "mx":
+				target := firstNonEmpty(value, name)
+				mxs, _ := lookupMX(target)
+				for _, mx := range mxs {
+					m.addHost("spf", mx, via+" > mx:"+mx, firstNonEmpty(provider, mailProvider(mx)))
+				}
+			}
+		}
+	}
+}
+
+// dmarcAddresses 返回 DMARC rua / ruf 中 mailto 地址的域名
+func dmarcAddresses(record string) []string {
+	var domains []string
+	for _, tag := range strings.Split(record, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(tag), "=")
+		if !ok || (key != "rua" && key != "ruf") {
+			continue
+		}
+		for _, uri := range strings.Split(value, ",") {
+			if _, addr, ok := strings.Cut(strings.TrimSpace(uri), "@"); ok {
+				// 去掉 mailto 地址后可能附带的 !10m 大小限制
+				addr, _, _ = strings.Cut(addr, "!")
+				domains = appendUnique(domains, strings.ToLower(addr))
+			}
+		}
+	}
+	return domains
+}
+
+func lookupMX(name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	records, err := net.DefaultResolver.LookupMX(ctx, name)
+	var hosts []string
+	for _, r := range records {
+		hosts = appendUnique(hosts, strings.TrimSuffix(r.Host, "."))
+	}
+	return hosts, err
+}
+
+func lookupTXT(name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	return net.DefaultResolver.LookupTXT(ctx, name)
+}
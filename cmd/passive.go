@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+)
+
+// passiveStrategy 是不经过空间测绘引擎、直接产生候选的策略，如历史解析记录
+type passiveStrategy struct {
+	// Describe 返回 dry-run 时展示的说明
+	Describe func(host string) string
+	// Run 对目标主机执行查询并返回已排除 CDN 地址的结果
+	Run func(host string) ([]SearchResult, error)
+}
+
+// 已注册的被动策略，-p 指定这些策略时不需要加载搜索引擎
+var passiveStrategies = map[string]passiveStrategy{
+	"history": {
+		Describe: func(host string) string {
+			return fmt.Sprintf("passive DNS A-record history of %s (securitytrails, virustotal, generic endpoint)", host)
+		},
+		Run: historyLookup,
+	},
+	"ct": {
+		Describe: func(host string) string {
+			return fmt.Sprintf("certificate transparency names under %s from %s, resolved outside CDN ranges", registeredDomain(host), ctBaseURL)
+		},
+		Run: ctLookup,
+	},
+	"mail": {
+		Describe: func(host string) string {
+			return fmt.Sprintf("MX, SPF (include / a / mx / ip4 / ip6 expanded), DMARC and TXT records of %s", registeredDomain(host))
+		},
+		Run: mailLookup,
+	},
+	"subdomain": {
+		Describe: func(host string) string {
+			names, err := subdomainNames(registeredDomain(host))
+			if err != nil {
+				return fmt.Sprintf("origin-style subdomains of %s (%v)", registeredDomain(host), err)
+			}
+			return fmt.Sprintf("%d origin-style subdomain(s) of %s resolved with wildcard detection, outside CDN ranges", len(names), registeredDomain(host))
+		},
+		Run: subdomainLookup,
+	},
+	"resolvers": {
+		Describe: func(host string) string {
+			return fmt.Sprintf("A / AAAA records of %s from %d resolver(s) compared, addresses outside CDN ranges kept", host, len(resolvers()))
+		},
+		Run: resolversLookup,
+	},
+}
+
+// isPassive 判断策略是否为被动策略
+func isPassive(p string) bool {
+	_, ok := passiveStrategies[p]
+	return ok
+}
+
+// runPassive 执行被动策略，返回结果与失败数
+func runPassive(p string, input string) ([]SearchResult, int) {
+	// 被动策略都基于 DNS 或域名查询，与端口无关
+	host := stripPort(extractHost(input))
+	fmt.Printf("[+] [%s] %s ...\n", p, passiveStrategies[p].Describe(host))
+	results, err := passiveStrategies[p].Run(host)
+	failed := 0
+	if err != nil {
+		failed++
+		fmt.Printf("❌ [%s] lookup failed: %v\n", p, err)
+	}
+	var found []SearchResult
+	for _, r := range results {
+		if r.IP != "" {
+			r.Strategy = p
+			found = append(found, r)
+		}
+	}
+	return found, failed
+}
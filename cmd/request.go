@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// 各引擎默认的请求速率（次/秒）与突发容量，未列出的引擎使用 default
+var engineRateLimits = map[string]struct {
+	Rate  float64
+	Burst int
+}{
+	"fofa":    {1, 2},
+	"shodan":  {1, 1},
+	"zoomeye": {1, 1},
+	"censys":  {0.4, 2},
+	"hunter":  {0.5, 1},
+	"quake":   {1, 1},
+	"whatcms": {0.1, 1},
+	// 被动 DNS 数据源，VirusTotal 公共 API 限制为每分钟 4 次
+	"securitytrails": {1, 1},
+	"virustotal":     {0.066, 1},
+	"history":        {1, 1},
+	"crtsh":          {0.5, 1},
+	"doh":            {10, 10},
+	"internetdb":     {5, 5},
+	"default":        {1, 2},
+}
+
+const (
+	apiRetryWait    = 1 * time.Second
+	apiRetryMaxWait = 30 * time.Second
+)
+
+// FOFA 在 HTTP 200 的响应体中返回的限速错误码，命中时按退避重试
+var fofaRateLimitCodes = []string{"[45012]", "[45022]", "[820010]"}
+
+// apiRetries 是失败请求的最大重试次数，可由 --retries 覆盖
+var apiRetries = 3
+
+// tokenBucket 是简单的令牌桶限速器
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Wait 阻塞直到取得一个令牌
+func (b *tokenBucket) Wait() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		time.Sleep(wait)
+		b.tokens = 1
+		b.last = time.Now()
+	}
+	b.tokens--
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*tokenBucket)
+)
+
+func engineLimiter(engine string) *tokenBucket {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	if l, ok := limiters[engine]; ok {
+		return l
+	}
+	limit, ok := engineRateLimits[engine]
+	if !ok {
+		limit = engineRateLimits["default"]
+	}
+	l := newTokenBucket(limit.Rate, limit.Burst)
+	limiters[engine] = l
+	return l
+}
+
+// apiClient 返回所有外部 API 调用共用的 resty 客户端：按引擎限速，
+// 对网络错误、429/5xx 与 FOFA 限速错误码做带抖动的指数退避重试，并优先遵循 Retry-After
+func apiClient(engine string) *resty.Client {
+	limiter := engineLimiter(engine)
+	return resty.New().
+		SetTimeout(30 * time.Second).
+		SetRetryCount(apiRetries).
+		SetRetryWaitTime(apiRetryWait).
+		SetRetryMaxWaitTime(apiRetryMaxWait).
+		SetRetryAfter(retryAfter).
+		AddRetryCondition(func(r *resty.Response, err error) bool {
+			if err != nil {
+				return true
+			}
+			if r.StatusCode() == http.StatusTooManyRequests || r.StatusCode() >= 500 {
+				return true
+			}
+			return engine == "fofa" && isFofaRateLimited(r.String())
+		}).
+		OnBeforeRequest(func(c *resty.Client, r *resty.Request) error {
+			limiter.Wait()
+			return nil
+		})
+}
+
+// retryAfter 解析 Retry-After 头（秒数或 HTTP 日期），返回 0 时由 resty 使用指数退避
+func retryAfter(c *resty.Client, r *resty.Response) (time.Duration, error) {
+	if r == nil {
+		return 0, nil
+	}
+	value := strings.TrimSpace(r.Header().Get("Retry-After"))
+	if value == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t), nil
+	}
+	return 0, nil
+}
+
+func isFofaRateLimited(body string) bool {
+	for _, code := range fofaRateLimitCodes {
+		if strings.Contains(body, code) {
+			return true
+		}
+	}
+	return false
+}
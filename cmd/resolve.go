@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// 单次域名解析超时
+	resolveTimeout = 5 * time.Second
+	// 默认同时解析的域名数
+	resolveWorkers = 20
+)
+
+// resolveHosts 并发解析 names 的 A 与 AAAA 记录，workers 限制并发数，解析失败的域名不出现在结果中
+func resolveHosts(names []string, workers int) map[string][]string {
+	resolved := make(map[string][]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(workers, 1))
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			ips := lookupIPs(name)
+			if len(ips) == 0 {
+				return
+			}
+			mu.Lock()
+			resolved[name] = ips
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+	return resolved
+}
+
+// lookupIPs 返回域名的 IPv4 与 IPv6 地址
+func lookupIPs(name string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIP(ctx, "ip", name)
+	if err != nil {
+		return nil
+	}
+	var ips []string
+	for _, a := range addrs {
+		ips = appendUnique(ips, a.String())
+	}
+	return ips
+}
+
+// lookupCNAME 返回系统解析器给出的规范名，没有 CNAME 时返回域名本身
+func lookupCNAME(name string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	cname, err := net.DefaultResolver.LookupCNAME(ctx, name)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(cname, ".")
+}
+
+// registeredDomain 返回主机名的注册域名（如 a.b.example.co.uk 返回 example.co.uk），无法识别时返回主机名本身
+func registeredDomain(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"GoUnder/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// 默认对比的公共解析器，覆盖不同地区与运营商，URL 形式的为 DoH（JSON 格式）接口
+var defaultResolvers = []string{
+	"8.8.8.8",
+	"1.1.1.1",
+	"9.9.9.9",
+	"208.67.222.222",
+	"223.5.5.5",
+	"119.29.29.29",
+	"114.114.114.114",
+	"180.76.76.76",
+	"77.88.8.8",
+	"https://dns.google/resolve",
+	"https://cloudflare-dns.com/dns-query",
+}
+
+// resolverList 为 --resolvers 指定的解析器，逗号分隔，为空时使用 defaultResolvers
+var resolverList string
+
+// resolverAnswer 是一个解析器对目标的解析结果
+type resolverAnswer struct {
+	Resolver string
+	IPs      []string
+	Err      error
+}
+
+// dohResponse 是 DoH JSON 接口（application/dns-json）的响应
+type dohResponse struct {
+	Status int `json:"Status"`
+	Answer []struct {
+		Type int    `json:"type"`
+		Data string `json:"data"`
+	} `json:"Answer"`
+}
+
+var dnsCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "DNS utilities.",
+}
+
+var dnsCompareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Resolve the target through multiple resolvers and compare the answers.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if targetURL == "" {
+			fmt.Println("❗  use -u for target")
+			_ = cmd.Usage()
+			os.Exit(1)
+		}
+		host := stripPort(extractHost(targetURL))
+		answers := compareResolvers(host, resolvers())
+		printResolverTable(answers)
+		printResolverSummary(answers)
+	},
+}
+
+// resolvers 返回本次使用的解析器列表
+func resolvers() []string {
+	if list := searchFieldList(resolverList); len(list) > 0 {
+		return list
+	}
+	return defaultResolvers
+}
+
+// resolversLookup 通过多个解析器解析目标，返回不属于 CDN 的地址，用于发现按地区调度或内外网分离解析的源站
+func resolversLookup(host string) ([]SearchResult, error) {
+	answers := compareResolvers(host, resolvers())
+	printResolverTable(answers)
+
+	var results []SearchResult
+	failed := 0
+	for _, a := range answers {
+		if a.Err != nil {
+			failed++
+			continue
+		}
+		for _, ip := range a.IPs {
+			if utils.IsCDNIP(ip) {
+				continue
+			}
+			results = append(results, SearchResult{IP: ip, Host: host, Source: "dns", Extra: map[string]string{"resolvers": a.Resolver}})
+		}
+	}
+	if failed == len(answers) {
+		return nil, fmt.Errorf("all %d resolver(s) failed", failed)
+	}
+	return results, nil
+}
+
+// compareResolvers 并发地通过每个解析器查询 host 的 A 与 AAAA 记录，结果顺序与 list 一致
+func compareResolvers(host string, list []string) []resolverAnswer {
+	answers := make([]resolverAnswer, len(list))
+	var wg sync.WaitGroup
+	for i, r := range list {
+		wg.Add(1)
+		go func(i int, r string) {
+			defer wg.Done()
+			var ips []string
+			var err error
+			if strings.HasPrefix(r, "https://") || strings.HasPrefix(r, "http://") {
+				ips, err = lookupDoH(r, host)
+			} else {
+				ips, err = lookupUDP(r, host)
+			}
+			sort.Strings(ips)
+			answers[i] = resolverAnswer{Resolver: r, IPs: ips, Err: err}
+		}(i, r)
+	}
+	wg.Wait()
+	return answers
+}
+
+// lookupUDP 通过指定的 DNS 服务器（ip 或 ip:port）查询 A 与 AAAA 记录
+func lookupUDP(server, host string) ([]string, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	resolver := &net.Resolver{
+		PreferGo: true,
+		// 应答被截断时解析器会以 tcp 重试，network 需原样传递
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	addrs, err := resolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	var ips []string
+	for _, a := range addrs {
+		ips = appendUnique(ips, a.String())
+	}
+	return ips, nil
+}
+
+// DoH JSON 接口中 A 与 AAAA 记录的类型编号
+var dohTypes = map[string]int{"A": 1, "AAAA": 28}
+
+// lookupDoH 通过 DoH JSON 接口查询 A 与 AAAA 记录，两者都失败时返回错误
+func lookupDoH(endpoint, host string) ([]string, error) {
+	var ips []string
+	var errs []error
+	for _, qtype := range []string{"A", "AAAA"} {
+		found, err := lookupDoHType(endpoint, host, qtype)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ips = append(ips, found...)
+	}
+	if len(errs) == len(dohTypes) {
+		return nil, errs[0]
+	}
+	return ips, nil
+}
+
+func lookupDoHType(endpoint, host, qtype string) ([]string, error) {
+	var result dohResponse
+	resp, err := apiClient("doh").R().
+		SetHeader("Accept", "application/dns-json").
+		SetQueryParams(map[string]string{"name": host, "type": qtype}).
+		SetResult(&result).
+		ForceContentType("application/json").
+		Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("request DoH endpoint failed: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("DoH endpoint return error: %s", resp.Status())
+	}
+	if result.Status != 0 {
+		return nil, fmt.Errorf("DoH endpoint return rcode %d", result.Status)
+	}
+	var ips []string
+	for _, a := range result.Answer {
+		// 跳过 CNAME 链
+		if a.Type == dohTypes[qtype] {
+			ips = appendUnique(ips, normalizeIP(a.Data))
+		}
+	}
+	return ips, nil
+}
+
+// printResolverTable 按解析器输出解析结果，不属于 CDN 的地址以 * 标记
+func printResolverTable(answers []resolverAnswer) {
+	var rows [][]string
+	for _, a := range answers {
+		if a.Err != nil {
+			rows = append(rows, []string{a.Resolver, "error", shorten(a.Err.Error(), 80)})
+			continue
+		}
+		marked := make([]string, len(a.IPs))
+		for i, ip := range a.IPs {
+			marked[i] = ip
+			if !utils.IsCDNIP(ip) {
+				marked[i] += "*"
+			}
+		}
+		rows = append(rows, []string{a.Resolver, "ok", strings.Join(marked, ", ")})
+	}
+	fmt.Println()
+	printTable([]string{"resolver", "status", "answer (* = outside CDN ranges)"}, rows)
+	fmt.Println()
+}
+
+// printResolverSummary 按地址汇总返回它的解析器数量
+func printResolverSummary(answers []resolverAnswer) {
+	seenBy := make(map[string][]string)
+	ok := 0
+	for _, a := range answers {
+		if a.Err != nil {
+			continue
+		}
+		ok++
+		for _, ip := range a.IPs {
+			seenBy[ip] = append(seenBy[ip], a.Resolver)
+		}
+	}
+	if len(seenBy) == 0 {
+		fmt.Println("❌ No answer.")
+		return
+	}
+
+	ips := make([]string, 0, len(seenBy))
+	for ip := range seenBy {
+		ips = append(ips, ip)
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		if len(seenBy[ips[i]]) != len(seenBy[ips[j]]) {
+			return len(seenBy[ips[i]]) > len(seenBy[ips[j]])
+		}
+		return ips[i] < ips[j]
+	})
+
+	var rows [][]string
+	origins := 0
+	for _, ip := range ips {
+		cdn := "yes"
+		if !utils.IsCDNIP(ip) {
+			cdn = "no"
+			origins++
+		}
+		rows = append(rows, []string{ip, fmt.Sprintf("%d/%d", len(seenBy[ip]), ok), cdn})
+	}
+	printTable([]string{"ip", "resolvers", "cdn (by ip range)"}, rows)
+	if origins > 0 {
+		fmt.Printf("\n✅ %d address(es) outside CDN ranges\n", origins)
+	}
+	// 没有 IP 段与 ASN 数据的服务商只能通过 CNAME 与响应头识别，cdn 为 no 不代表不是它们的节点
+	if names := utils.UnrecognizedProviders(); len(names) > 0 && origins > 0 {
+		fmt.Printf("⚠️  Not recognized by IP, check with detect: %s\n", strings.Join(names, ", "))
+	}
+}
+
+func init() {
+	dnsCompareCmd.Flags().StringVarP(&targetURL, "url", "u", "", "targetURL, eg: https://example.com")
+	dnsCompareCmd.Flags().StringVarP(&resolverList, "resolvers", "", "", "resolvers to compare, comma separated ip[:port] or DoH JSON URL (default: built-in public resolvers)")
+	dnsCmd.AddCommand(dnsCompareCmd)
+	rootCmd.AddCommand(dnsCmd)
+}
@@ -13,4 +13,7 @@ func Execute() {
 	cobra.CheckErr(rootCmd.Execute())
 }
 
-func init() {}
+func init() {
+	// 重试次数对所有发送 API 请求的子命令生效，只在根命令上定义一次
+	rootCmd.PersistentFlags().IntVarP(&apiRetries, "retries", "", 3, "max retries for failed API requests")
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// strategyWeights 是各策略命中时的得分，证书与 favicon 几乎只属于目标自身，title 最容易被其他站点复用
+var strategyWeights = map[string]int{
+	"cert":      30,
+	"icon":      30,
+	"history":   25,
+	"host":      20,
+	"resolvers": 20,
+	"ct":        15,
+	"subdomain": 15,
+	"mail":      10,
+	"title":     10,
+}
+
+const (
+	// 属于常见邮件服务商的地址的扣分
+	mailProviderPenalty = 25
+	// 每多一个引擎或数据源命中的加分
+	extraEngineWeight = 5
+	// 候选承载的无关主机名超过该数量时视为共享主机
+	sharedHostingThreshold = 5
+	// 每个超出阈值的无关主机名的扣分及扣分上限
+	sharedHostingPenalty    = 3
+	sharedHostingMaxPenalty = 30
+)
+
+// 验证结论与证书检查的得分
+var verdictWeights = map[string]int{
+	verdictConfirmed: 40,
+	verdictRelated:   15,
+	verdictUnrelated: -10,
+}
+
+// scoreCandidates 根据命中的策略、引擎、共享主机情况及验证结果为候选打分，并按得分降序排列
+func scoreCandidates(input string, candidates []Candidate) {
+	domain := registeredDomain(extractHost(input))
+	for i := range candidates {
+		candidates[i].score(domain)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+}
+
+func (c *Candidate) score(domain string) {
+	c.Score, c.Reasons = 0, nil
+	add := func(points int, reason string) {
+		c.Score += points
+		c.Reasons = append(c.Reasons, fmt.Sprintf("%s %+d", reason, points))
+	}
+
+	for _, s := range c.Strategies {
+		if w, ok := strategyWeights[s]; ok {
+			add(w, s)
+		}
+	}
+	if n := len(c.Sources); n > 1 {
+		add(extraEngineWeight*(n-1), fmt.Sprintf("%d sources", n))
+	}
+
+	// 查询结果中的主机名大多属于目标自身，共享主机以同 IP 上的全部主机名判断
+	unrelated := 0
+	seen := make(map[string]bool)
+	for _, h := range append(append([]string{}, c.Hostnames...), c.CoHosts...) {
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		if domain != "" && registeredDomain(extractHost(h)) != domain {
+			unrelated++
+		}
+	}
+	if unrelated > sharedHostingThreshold {
+		penalty := min(sharedHostingPenalty*(unrelated-sharedHostingThreshold), sharedHostingMaxPenalty)
+		add(-penalty, fmt.Sprintf("shared hosting (%d unrelated hosts)", unrelated))
+	}
+
+	if providers := c.Extra["mail_provider"]; len(providers) > 0 {
+		add(-mailProviderPenalty, "mail provider "+strings.Join(providers, ","))
+	}
+
+	if v := c.Verification; v != nil {
+		add(verdictWeights[v.Verdict], "verify "+v.Verdict)
+	}
+	switch c.certSummary() {
+	case "cdn-match":
+		add(30, "tls matches CDN certificate")
+	case "covers":
+		add(20, "tls covers target")
+	}
+}
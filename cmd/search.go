@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"GoUnder/query"
+	"GoUnder/utils"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchQuery  string
+	searchFields string
+	noCDNFilter  bool
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search",
+	Short: "Run a raw FOFA query with CDN exclusions appended.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if searchQuery == "" {
+			fmt.Println("❗  use -q for FOFA query")
+			_ = cmd.Usage()
+			os.Exit(1)
+		}
+		fields := searchFieldList(searchFields)
+		if len(fields) == 0 {
+			fmt.Println("❗  --fields must not be empty")
+			os.Exit(1)
+		}
+
+		q, err := buildSearchQuery(searchQuery, !noCDNFilter)
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		if dryRun {
+			encoded := base64.StdEncoding.EncodeToString([]byte(q))
+			fmt.Printf("\n🧪 Dry run, nothing sent:\n   query  (%d bytes): %s\n   base64 (%d bytes): %s\n", len(q), q, len(encoded), encoded)
+			return
+		}
+
+		fofaCfg, err = loadFofaConfig()
+		if err != nil {
+			log.Fatalf("Error loading fofa config: %v\n", err)
+		}
+		rows, err := rawSearch(q, strings.Join(fields, ","))
+		if err != nil {
+			fmt.Println("❌ [fofa] search failed:", err)
+			os.Exit(1)
+		}
+		if len(rows) == 0 {
+			fmt.Println("\n❌ No result.")
+			return
+		}
+		fmt.Printf("\n✅ %d result(s):\n\n", len(rows))
+		printTable(fields, rows)
+	},
+}
+
+// searchFieldList 解析逗号分隔的返回字段
+func searchFieldList(fields string) []string {
+	var list []string
+	for _, f := range strings.Split(fields, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			list = append(list, f)
+		}
+	}
+	return list
+}
+
+// buildSearchQuery 按需校验用户输入的 FOFA 查询并追加 CDN 排除规则
+func buildSearchQuery(raw string, cdnFilter bool) (string, error) {
+	// 不追加排除规则时无需改写，原样交给 FOFA，解析器不认识的语法也能使用
+	if !cdnFilter {
+		return strings.TrimSpace(raw), nil
+	}
+	n, err := query.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid FOFA query: %v (use --no-cdn-filter to send it unchanged)", err)
+	}
+	// 经语法树拼接，用户查询中的 || 会被加上括号
+	q, err := query.Render(query.AndOf(n, utils.FofaFilter()), query.FOFA)
+	if err != nil {
+		return "", fmt.Errorf("invalid FOFA query: %v (use --no-cdn-filter to send it unchanged)", err)
+	}
+	return q, nil
+}
+
+// rawSearch 执行查询并去重，结果写入查询缓存
+func rawSearch(q string, fields string) ([][]string, error) {
+	cacheKey := fmt.Sprintf("%s;size=%d;pages=%d;all=%v", fields, fofaSize, fofaMaxPages, fofaAll)
+	var rows [][]string
+	if !cacheGet("fofa", q, cacheKey, &rows) {
+		var err error
+		rows, err = Query(base64.StdEncoding.EncodeToString([]byte(q)), fields)
+		if err != nil {
+			return nil, err
+		}
+		cachePut("fofa", q, cacheKey, len(rows), rows)
+	}
+	return unique2D(rows), nil
+}
+
+func printTable(header []string, rows [][]string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	seps := make([]string, len(header))
+	for i, h := range header {
+		seps[i] = strings.Repeat("-", len(h))
+	}
+	fmt.Fprintln(w, strings.Join(seps, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	_ = w.Flush()
+}
+
+func init() {
+	searchCmd.Flags().StringVarP(&searchQuery, "query", "q", "", `FOFA query, eg: body="xxx"`)
+	searchCmd.Flags().StringVarP(&searchFields, "fields", "", "ip,port,host,title,org,country", "returned FOFA fields, comma separated")
+	searchCmd.Flags().BoolVarP(&noCDNFilter, "no-cdn-filter", "", false, "do not append the CDN exclusion rules")
+	searchCmd.Flags().IntVarP(&fofaSize, "size", "", 100, "FOFA results per page (max 10000)")
+	searchCmd.Flags().IntVarP(&fofaMaxPages, "max-pages", "", 1, "max FOFA pages fetched")
+	searchCmd.Flags().BoolVarP(&fofaAll, "all", "", false, "fetch all FOFA results, ignore --max-pages")
+	searchCmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, "only print the final query, send nothing")
+	searchCmd.Flags().BoolVarP(&noCache, "no-cache", "", false, "do not read or write the local query cache")
+	searchCmd.Flags().BoolVarP(&refreshCache, "refresh", "", false, "ignore cached results and refresh the cache")
+	searchCmd.Flags().DurationVarP(&cacheTTL, "cache-ttl", "", cacheTTL, "reuse cached query results younger than this")
+	searchCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "verbose output, eg: which FOFA key served each query")
+	rootCmd.AddCommand(searchCmd)
+}
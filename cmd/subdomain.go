@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"GoUnder/utils"
+)
+
+// 源站常用的子域名前缀，常见于绕过 CDN 直连源站的配置
+var subdomainPrefixes = []string{"origin", "direct", "real", "backend", "dev", "test", "staging", "old"}
+
+var (
+	// subdomainWordlist 为 --wordlist 指定的字典文件，每行一个前缀
+	subdomainWordlist string
+	// subdomainWorkers 为 -p subdomain 同时解析的域名数
+	subdomainWorkers = resolveWorkers
+)
+
+// subdomainLookup 解析目标注册域名下的常见源站子域名与字典中的子域名，
+// 排除泛解析与 CDN 地址后返回剩余的 IP
+func subdomainLookup(host string) ([]SearchResult, error) {
+	domain := registeredDomain(host)
+	names, err := subdomainNames(domain)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[+] [subdomain] resolving %d name(s) under %s with %d worker(s)...\n", len(names), domain, max(subdomainWorkers, 1))
+
+	wildcards := wildcardIPs(names)
+	resolved := resolveHosts(names, subdomainWorkers)
+	var results []SearchResult
+	wildcarded, behindCDN := 0, 0
+	for _, name := range names {
+		ips, ok := resolved[name]
+		if !ok {
+			continue
+		}
+		// 解析结果与泛解析完全相同的域名并不真实存在
+		if wild := wildcards[parentDomain(name)]; wild != nil && isSubset(ips, wild) {
+			wildcarded++
+			continue
+		}
+		for _, ip := range ips {
+			if utils.IsCDNIP(ip) {
+				behindCDN++
+				continue
+			}
+			results = append(results, SearchResult{IP: ip, Host: name, Source: "dns"})
+		}
+	}
+	fmt.Printf("[+] [subdomain] %d name(s) resolved, %d matched wildcard DNS, %d address(es) inside CDN ranges dropped\n",
+		len(resolved), wildcarded, behindCDN)
+	return results, nil
+}
+
+// subdomainNames 返回内置前缀与字典前缀拼接 domain 后的去重域名列表
+func subdomainNames(domain string) ([]string, error) {
+	prefixes := subdomainPrefixes
+	if subdomainWordlist != "" {
+		words, err := readWordlist(subdomainWordlist)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(append([]string{}, prefixes...), words...)
+	}
+
+	var names []string
+	for _, p := range prefixes {
+		p = strings.Trim(strings.ToLower(p), ".")
+		if p == "" {
+			continue
+		}
+		// 字典中也可以直接写完整域名
+		if p != domain && !strings.HasSuffix(p, "."+domain) {
+			p += "." + domain
+		}
+		names = appendUnique(names, p)
+	}
+	return names, nil
+}
+
+// readWordlist 读取字典文件，忽略空行与 # 开头的注释
+func readWordlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open wordlist failed: %w", err)
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read wordlist failed: %w", err)
+	}
+	return words, nil
+}
+
+// wildcardIPs 对 names 涉及的每个上级域名解析一个随机子域名，返回存在泛解析的上级域名及其地址
+func wildcardIPs(names []string) map[string][]string {
+	var probes []string
+	probeOf := make(map[string]string)
+	for _, name := range names {
+		parent := parentDomain(name)
+		if _, ok := probeOf[parent]; ok {
+			continue
+		}
+		probe := randomLabel() + "." + parent
+		probeOf[parent] = probe
+		probes = append(probes, probe)
+	}
+
+	resolved := resolveHosts(probes, subdomainWorkers)
+	wildcards := make(map[string][]string)
+	for parent, probe := range probeOf {
+		if ips, ok := resolved[probe]; ok {
+			sort.Strings(ips)
+			fmt.Printf("⚠️  [subdomain] wildcard DNS detected on *.%s -> %s\n", parent, strings.Join(ips, ","))
+			wildcards[parent] = ips
+		}
+	}
+	return wildcards
+}
+
+func parentDomain(name string) string {
+	_, parent, _ := strings.Cut(name, ".")
+	return parent
+}
+
+func randomLabel() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "gounder-" + hex.EncodeToString(b)
+}
+
+// isSubset 判断 ips 是否全部出现在 set 中
+func isSubset(ips, set []string) bool {
+	for _, ip := range ips {
+		found := false
+		for _, s := range set {
+			if ip == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
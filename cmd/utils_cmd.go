@@ -2,16 +2,29 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"net"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"GoUnder/utils"
 )
 
 // cdn cmd definition
 type FofaConfig struct {
+	Email    string        `json:"email"`
+	Key      string        `json:"key"`
+	BaseURL  string        `json:"base_url,omitempty"`
+	Accounts []FofaAccount `json:"accounts,omitempty"`
+}
+
+// FofaAccount 是 fofa.json 中的一组 FOFA 凭据
+type FofaAccount struct {
 	Email string `json:"email"`
 	Key   string `json:"key"`
 }
@@ -21,12 +34,30 @@ type FofaResponse struct {
 	Results [][]string      `json:"-"`
 	Msg     string          `json:"errmsg"`
 	Raw     json.RawMessage `json:"results"`
+	Size    int             `json:"size"`
+	Page    int             `json:"page"`
+	Next    string          `json:"next"`
 }
 
+// FOFA 单页最大返回条数
+const fofaMaxSize = 10000
+
 var targetURL string
 var pattern string
 var fofaCfg *FofaConfig
+var fofaPool *fofaKeyPool
+var verbose bool
 var logFlag bool
+var engineNames string
+var fofaSize int
+var fofaMaxPages int
+var fofaAll bool
+var fofaBudget int
+var fofaBudgetTrim bool
+var dryRun bool
+var cdnExtraFields string
+var verifyFlag bool
+var verifyTLS bool
 
 // fingerprint cmd definition
 
@@ -41,7 +72,8 @@ var engine string
 func saveToLog(input string, content string) {
 	// 1. 提取主机名作为文件名
 	host := extractHost(input)
-	host = strings.ReplaceAll(host, ":", "_") // 防止 Windows 下端口号导致的文件名非法
+	// 防止 Windows 下端口号与 IPv6 地址中的冒号导致的文件名非法
+	host = strings.NewReplacer("[", "", "]", "", ":", "_").Replace(host)
 
 	// 2. 创建 logs 目录
 	logDir := "logs"
@@ -69,10 +101,99 @@ func saveToLog(input string, content string) {
 	}
 }
 
+// extractHost 返回输入中的主机名，带端口时保留端口，不带端口的 IPv6 地址去掉方括号
 func extractHost(raw string) string {
 	if !strings.HasPrefix(raw, "http://") && !strings.HasPrefix(raw, "https://") {
+		if strings.HasPrefix(raw, "[") && strings.HasSuffix(raw, "]") {
+			return raw[1 : len(raw)-1]
+		}
 		return raw
 	}
 	u, _ := url.Parse(raw)
+	if u.Port() == "" {
+		return u.Hostname()
+	}
 	return u.Host
 }
+
+// stripPort 去掉 extractHost 结果中的端口，返回用于 DNS 查询的主机名
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// urlHost 返回可直接拼入 URL 的主机名，IPv6 地址加上方括号
+func urlHost(host string) string {
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// displayIP 返回用于输出与日志的地址，IPv6 地址加上方括号，网段原样返回
+func displayIP(ip string) string {
+	if strings.Contains(ip, "/") {
+		return ip
+	}
+	return urlHost(ip)
+}
+
+// normalizeIP 统一 IP 的文本形式（如 IPv6 的大小写与零压缩），无法解析时原样返回
+func normalizeIP(ip string) string {
+	ip = strings.TrimSuffix(strings.TrimPrefix(ip, "["), "]")
+	if parsed := net.ParseIP(ip); parsed != nil {
+		return parsed.String()
+	}
+	return ip
+}
+
+// loadConfigFile 依次读取 configs/<filename> 与系统配置目录下的同名文件并解析到 cfg，
+// 均不存在时以 cfg 当前内容作为默认配置写入系统配置目录
+func loadConfigFile(filename string, cfg interface{}) error {
+	data, err := os.ReadFile(filepath.Join("configs", filename))
+	if err == nil {
+		return json.Unmarshal(data, cfg)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	configDir, err := utils.ConfigDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(configDir, filename)
+	data, err = os.ReadFile(path)
+	if err == nil {
+		return json.Unmarshal(data, cfg)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	log.Printf("Config file not found: %s\n", path)
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("creating config failed: %w", err)
+	}
+	defaultData, _ := json.MarshalIndent(cfg, "", "  ")
+	if err := os.WriteFile(path, defaultData, 0644); err != nil {
+		return fmt.Errorf("writing config file failed: %w", err)
+	}
+	log.Printf("Config file created: %s\n❗ Please complete the config file: %s", path, path)
+	return fmt.Errorf("config file %s is not completed", path)
+}
+
+// appendUnique 追加不重复的非空值
+func appendUnique(list []string, value string) []string {
+	if value == "" {
+		return list
+	}
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"GoUnder/utils"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 单次验证请求超时
+	verifyTimeout = 8 * time.Second
+	// 同时验证的候选数
+	verifyWorkers = 10
+	// 参与比较的响应体最大长度
+	verifyBodyLimit = 1 << 20
+)
+
+// 验证结论
+const (
+	verdictConfirmed = "confirmed"
+	verdictRelated   = "related"
+	verdictUnrelated = "unrelated"
+)
+
+// 因 CDN 或请求时间而变化的响应头，不参与比较
+var volatileHeaders = map[string]bool{
+	"age": true, "cf-cache-status": true, "cf-ray": true, "content-length": true, "date": true,
+	"expires": true, "last-modified": true, "nel": true, "report-to": true, "server-timing": true,
+	"set-cookie": true, "via": true, "x-amz-cf-id": true, "x-amz-cf-pop": true, "x-cache": true,
+	"x-cache-hits": true, "x-served-by": true, "x-timer": true, "alt-svc": true, "etag": true,
+}
+
+// Verification 是直连候选 IP 并携带目标 Host 头请求后，与经 CDN 访问结果的比较
+type Verification struct {
+	Score   int      `json:"score"`
+	Verdict string   `json:"verdict"`
+	URL     string   `json:"url,omitempty"`
+	Status  int      `json:"status,omitempty"`
+	Title   string   `json:"title,omitempty"`
+	Reasons []string `json:"reasons,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// pageSnapshot 是一次 HTTP 访问中用于比较的部分
+type pageSnapshot struct {
+	URL     string
+	Status  int
+	Title   string
+	Words   map[string]bool
+	Headers map[string]bool
+	Favicon string
+}
+
+// verifyCandidates 以目标 Host 头分别通过 HTTP 与 HTTPS 直连每个候选的各个端口，与经 CDN 的响应比较并给出结论
+func verifyCandidates(input string, candidates []Candidate) {
+	host := extractHost(input)
+	fmt.Printf("\n[+] Verifying %d candidate(s) with Host: %s ...\n", len(candidates), host)
+	baseline, err := fetchBaseline(host)
+	if err != nil {
+		fmt.Printf("❌ Fetch %s through CDN failed, verification skipped: %v\n", host, err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, verifyWorkers)
+	for i := range candidates {
+		if candidates[i].IsNetwork() {
+			continue
+		}
+		wg.Add(1)
+		go func(c *Candidate) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			v := verifyCandidate(host, c, baseline)
+			c.Verification = &v
+		}(&candidates[i])
+	}
+	wg.Wait()
+
+	for _, c := range candidates {
+		v := c.Verification
+		if v == nil {
+			continue
+		}
+		if v.Error != "" {
+			fmt.Printf("- %s  %s (%d)  %s\n", displayIP(c.IP), v.Verdict, v.Score, v.Error)
+			continue
+		}
+		fmt.Printf("- %s  %s (%d)  %s  [%s]\n", displayIP(c.IP), v.Verdict, v.Score, v.URL, strings.Join(v.Reasons, ", "))
+	}
+}
+
+// fetchBaseline 经 CDN 正常访问目标，优先 HTTPS
+func fetchBaseline(host string) (*pageSnapshot, error) {
+	client := verifyClient("", "")
+	var lastErr error
+	for _, scheme := range []string{"https", "http"} {
+		snap, err := fetchSnapshot(client, scheme+"://"+urlHost(host)+"/")
+		if err == nil {
+			return snap, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// verifyCandidate 返回候选各端口、各协议中得分最高的比较结果
+func verifyCandidate(host string, c *Candidate, baseline *pageSnapshot) Verification {
+	ports := c.Ports
+	if len(ports) == 0 {
+		ports = []string{"80", "443"}
+	}
+	best := Verification{Verdict: verdictUnrelated}
+	var errs []string
+	for _, port := range ports {
+		client := verifyClient(c.IP, port)
+		for _, scheme := range []string{"http", "https"} {
+			// URL 中不带端口，保证 Host 头与 SNI 都是目标主机名，端口由 verifyClient 拨号决定
+			snap, err := fetchSnapshot(client, scheme+"://"+urlHost(host)+"/")
+			if err != nil {
+				errs = append(errs, scheme+"/"+port)
+				continue
+			}
+			snap.URL = scheme + "://" + net.JoinHostPort(c.IP, port)
+			v := compareSnapshots(baseline, snap)
+			if best.URL == "" || v.Score > best.Score {
+				best = v
+			}
+		}
+	}
+	if best.URL == "" {
+		best.Error = "no response on " + strings.Join(errs, ", ")
+	}
+	return best
+}
+
+// compareSnapshots 比较状态码、title、正文相似度、响应头集合与 favicon，得分 0-100
+func compareSnapshots(baseline, snap *pageSnapshot) Verification {
+	v := Verification{URL: snap.URL, Status: snap.Status, Title: snap.Title}
+	score := 0.0
+	if snap.Status == baseline.Status {
+		score += 15
+		v.Reasons = append(v.Reasons, fmt.Sprintf("status %d", snap.Status))
+	}
+	if baseline.Title != "" && snap.Title == baseline.Title {
+		score += 25
+		v.Reasons = append(v.Reasons, "same title")
+	}
+	if sim := jaccard(baseline.Words, snap.Words); sim > 0 {
+		score += 30 * sim
+		v.Reasons = append(v.Reasons, fmt.Sprintf("body %.0f%%", sim*100))
+	}
+	if sim := jaccard(baseline.Headers, snap.Headers); sim > 0 {
+		score += 10 * sim
+		v.Reasons = append(v.Reasons, fmt.Sprintf("headers %.0f%%", sim*100))
+	}
+	if baseline.Favicon != "" && snap.Favicon == baseline.Favicon {
+		score += 20
+		v.Reasons = append(v.Reasons, "same favicon")
+	}
+	v.Score = int(score + 0.5)
+	switch {
+	case v.Score >= 70:
+		v.Verdict = verdictConfirmed
+	case v.Score >= 40:
+		v.Verdict = verdictRelated
+	default:
+		v.Verdict = verdictUnrelated
+	}
+	return v
+}
+
+// verifyClient 返回验证用的 http.Client。ip 非空时所有连接都直接拨到 ip:port，
+// URL 中的主机名仍用作 Host 头与 SNI
+func verifyClient(ip string, port string) *http.Client {
+	dialer := &net.Dialer{Timeout: verifyTimeout}
+	transport := &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	}
+	if ip != "" {
+		addr := net.JoinHostPort(ip, port)
+		transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+	return &http.Client{
+		Timeout:   verifyTimeout,
+		Transport: transport,
+		// 不跟随跳转，跳转目标可能重新经过 CDN
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+func fetchSnapshot(c *http.Client, target string) (*pageSnapshot, error) {
+	resp, err := c.Get(target)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, verifyBodyLimit))
+
+	snap := &pageSnapshot{
+		URL:     target,
+		Status:  resp.StatusCode,
+		Title:   htmlTitle(string(body)),
+		Words:   wordSet(string(body)),
+		Headers: make(map[string]bool),
+	}
+	for name := range resp.Header {
+		if name = strings.ToLower(name); !volatileHeaders[name] {
+			snap.Headers[name] = true
+		}
+	}
+	if fav, err := c.Get(strings.TrimSuffix(target, "/") + "/favicon.ico"); err == nil {
+		data, _ := io.ReadAll(io.LimitReader(fav.Body, verifyBodyLimit))
+		fav.Body.Close()
+		if fav.StatusCode == http.StatusOK && len(data) > 0 {
+			snap.Favicon = utils.Mmh3Hash32(data)
+		}
+	}
+	return snap, nil
+}
+
+// htmlTitle 提取页面 <title> 内容
+func htmlTitle(body string) string {
+	lower := strings.ToLower(body)
+	start := strings.Index(lower, "<title")
+	if start == -1 {
+		return ""
+	}
+	open := strings.Index(lower[start:], ">")
+	end := strings.Index(lower[start:], "</title>")
+	if open == -1 || end == -1 || open > end {
+		return ""
+	}
+	return strings.TrimSpace(body[start+open+1 : start+end])
+}
+
+func wordSet(body string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.Fields(body) {
+		words[w] = true
+	}
+	return words
+}
+
+// jaccard 返回两个集合的 Jaccard 相似度
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for k := range a {
+		if b[k] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// CertInfo 是以目标主机名作为 SNI 连接候选 IP 时得到的叶子证书
+type CertInfo struct {
+	Port   string   `json:"port"`
+	SANs   []string `json:"sans"`
+	Issuer string   `json:"issuer"`
+	Serial string   `json:"serial"`
+	SHA256 string   `json:"sha256"`
+	// CoversTarget 表示证书的 SAN 覆盖目标域名
+	CoversTarget bool `json:"coversTarget"`
+	// MatchesCDN 表示与经 CDN 访问时得到的证书相同
+	MatchesCDN bool `json:"matchesCdn"`
+}
+
+// verifyCandidatesTLS 以目标主机名作为 SNI 连接每个候选的 443 及其余上报端口，记录叶子证书并与目标域名、CDN 证书比对
+func verifyCandidatesTLS(input string, candidates []Candidate) {
+	hostname := extractHost(input)
+	if h, _, err := net.SplitHostPort(hostname); err == nil {
+		hostname = h
+	}
+	fmt.Printf("\n[+] Checking TLS certificates of %d candidate(s) with SNI: %s ...\n", len(candidates), hostname)
+	cdnSHA256 := ""
+	if cert, err := leafCert(net.JoinHostPort(hostname, "443"), hostname); err == nil {
+		cdnSHA256 = certSHA256(cert)
+	} else {
+		fmt.Printf("⚠️  Fetch certificate of %s through CDN failed: %v\n", hostname, err)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, verifyWorkers)
+	for i := range candidates {
+		if candidates[i].IsNetwork() {
+			continue
+		}
+		wg.Add(1)
+		go func(c *Candidate) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			c.Certs = candidateCerts(hostname, c, cdnSHA256)
+		}(&candidates[i])
+	}
+	wg.Wait()
+
+	for _, c := range candidates {
+		if c.IsNetwork() {
+			continue
+		}
+		if len(c.Certs) == 0 {
+			fmt.Printf("- %s  no TLS handshake\n", displayIP(c.IP))
+			continue
+		}
+		for _, cert := range c.Certs {
+			fmt.Printf("- %s  %s  issuer=%s  serial=%s  sans=%s%s\n",
+				net.JoinHostPort(c.IP, cert.Port), cert.SHA256[:16], cert.Issuer, cert.Serial, shorten(strings.Join(cert.SANs, ","), 60), certMarks(cert))
+		}
+	}
+}
+
+// candidateCerts 依次尝试 443 与候选的其余端口，未完成 TLS 握手的端口被忽略
+func candidateCerts(hostname string, c *Candidate, cdnSHA256 string) []CertInfo {
+	ports := []string{"443"}
+	for _, p := range c.Ports {
+		ports = appendUnique(ports, p)
+	}
+	var certs []CertInfo
+	for _, p := range ports {
+		cert, err := leafCert(net.JoinHostPort(c.IP, p), hostname)
+		if err != nil {
+			continue
+		}
+		certs = append(certs, newCertInfo(p, cert, hostname, cdnSHA256))
+	}
+	return certs
+}
+
+func newCertInfo(port string, cert *x509.Certificate, hostname string, cdnSHA256 string) CertInfo {
+	info := CertInfo{
+		Port:         port,
+		SANs:         cert.DNSNames,
+		Issuer:       cert.Issuer.CommonName,
+		Serial:       cert.SerialNumber.Text(16),
+		SHA256:       certSHA256(cert),
+		CoversTarget: cert.VerifyHostname(hostname) == nil,
+	}
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+	if info.Issuer == "" && len(cert.Issuer.Organization) > 0 {
+		info.Issuer = cert.Issuer.Organization[0]
+	}
+	info.MatchesCDN = cdnSHA256 != "" && info.SHA256 == cdnSHA256
+	return info
+}
+
+func certMarks(cert CertInfo) string {
+	var marks []string
+	if cert.CoversTarget {
+		marks = append(marks, "covers target")
+	}
+	if cert.MatchesCDN {
+		marks = append(marks, "matches CDN certificate")
+	}
+	if len(marks) == 0 {
+		return ""
+	}
+	return "  ✔ " + strings.Join(marks, ", ")
+}
+
+// certSummary 返回候选证书检查的汇总标记，用于单行输出
+func (c Candidate) certSummary() string {
+	summary := "no-match"
+	for _, cert := range c.Certs {
+		switch {
+		case cert.MatchesCDN:
+			return "cdn-match"
+		case cert.CoversTarget:
+			summary = "covers"
+		}
+	}
+	return summary
+}
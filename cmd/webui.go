@@ -1,11 +1,13 @@
 package cmd
 
 import (
+	"GoUnder/utils"
 	"embed"
 	"fmt"
 	"io/fs"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,6 +23,10 @@ var staticFiles embed.FS
 var port string
 var host string
 
+// lookupMu 串行化 Web UI 的查询请求：查询选项经包级变量传给与命令行共用的查询逻辑，
+// 并发请求会互相覆盖对方的选项
+var lookupMu sync.Mutex
+
 var webuiCmd = &cobra.Command{
 	Use:   "webui",
 	Short: "Start web ui.",
@@ -84,28 +90,67 @@ func cdnHandler(c *gin.Context) {
 		})
 		return
 	}
+	lookupMu.Lock()
+	defer lookupMu.Unlock()
 	pattern = c.DefaultQuery("p", "")
+	engineNames = c.DefaultQuery("engines", "fofa")
+	dryRun = c.DefaultQuery("dry", "") == "true"
 
-	// 调用封装的函数获取真实 IP 和云服务
-	cdnLookupResult := cdnLookup(website)
-	var results []gin.H
-	for _, parts := range cdnLookupResult {
-		if len(parts) != 7 {
-			continue
+	// dry-run 只返回将要发送的查询
+	if dryRun {
+		planned, err := planDryRun(website)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		var pieces []gin.H
+		if usesFofa(planned) {
+			for _, piece := range utils.FofaRulePieces() {
+				pieces = append(pieces, gin.H{
+					"source": piece.Source,
+					"origin": piece.Origin,
+					"rules":  piece.Rules(),
+					"filter": piece.Node.String(),
+				})
+			}
 		}
-		results = append(results, gin.H{
-			"ip":      parts[0],
-			"port":    parts[1],
-			"host":    parts[2],
-			"org":     parts[3],
-			"country": parts[4],
-			"region":  parts[5],
-			"city":    parts[6],
+		c.JSON(http.StatusOK, gin.H{
+			"dryRun":       planned,
+			"filterPieces": pieces,
 		})
+		return
+	}
+
+	cdnExtraFields = c.DefaultQuery("fields", "")
+	verifyFlag = c.DefaultQuery("verify", "") == "true"
+	verifyTLS = c.DefaultQuery("verify_tls", "") == "true"
+
+	// 检测结果随响应返回，不经过 cdnLookup 内的检测
+	detectFirst = false
+	var detection *DetectResult
+	if c.DefaultQuery("detect", "") == "true" {
+		d, proceed := detectBeforeLookup(website)
+		detection = &d
+		if !proceed {
+			c.JSON(http.StatusOK, gin.H{
+				"cdnData": []Candidate{},
+				"detect":  detection,
+			})
+			return
+		}
+	}
+
+	// 调用封装的函数获取按 IP 聚合的候选源站
+	candidates := cdnLookup(website)
+	if candidates == nil {
+		candidates = []Candidate{}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"cdnData": results,
+		"cdnData": candidates,
+		"detect":  detection,
 	})
 }
 
@@ -117,6 +162,8 @@ func fpHandler(c *gin.Context) {
 		})
 		return
 	}
+	lookupMu.Lock()
+	defer lookupMu.Unlock()
 	engine = c.DefaultQuery("e", "")
 
 	var results []gin.H
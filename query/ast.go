@@ -0,0 +1,258 @@
+// Package query 提供 FOFA 查询语句的语法树、转义、解析，以及到其他测绘引擎查询语法的翻译
+package query
+
+import (
+	"strings"
+)
+
+// Op 是字段比较运算符，取值与 FOFA 语法一致
+type Op string
+
+const (
+	OpEq    Op = "="
+	OpExact Op = "=="
+	OpNe    Op = "!="
+	OpFuzzy Op = "*="
+)
+
+// Node 是查询语法树的节点，String 输出 FOFA 语法
+type Node interface {
+	String() string
+	node()
+}
+
+// Cmp 是字段比较，Field 为空时表示全文检索
+type Cmp struct {
+	Field string
+	Op    Op
+	Value string
+}
+
+// And 连接的所有子节点需同时满足
+type And struct {
+	Nodes []Node
+}
+
+// Or 连接的子节点满足其一即可
+type Or struct {
+	Nodes []Node
+}
+
+// Not 对子节点取反
+type Not struct {
+	Node Node
+}
+
+// Group 是显式的括号分组
+type Group struct {
+	Node Node
+}
+
+func (*Cmp) node()   {}
+func (*And) node()   {}
+func (*Or) node()    {}
+func (*Not) node()   {}
+func (*Group) node() {}
+
+func (n *Cmp) String() string   { return String(n) }
+func (n *And) String() string   { return String(n) }
+func (n *Or) String() string    { return String(n) }
+func (n *Not) String() string   { return String(n) }
+func (n *Group) String() string { return String(n) }
+
+// Eq 构造 field="value"
+func Eq(field, value string) Node {
+	return &Cmp{Field: field, Op: OpEq, Value: value}
+}
+
+// Ne 构造 field!="value"
+func Ne(field, value string) Node {
+	return &Cmp{Field: field, Op: OpNe, Value: value}
+}
+
+// Exact 构造 field=="value"
+func Exact(field, value string) Node {
+	return &Cmp{Field: field, Op: OpExact, Value: value}
+}
+
+// Text 构造全文检索 "value"
+func Text(value string) Node {
+	return &Cmp{Op: OpEq, Value: value}
+}
+
+// AndOf 以 && 连接节点，忽略 nil 并展开嵌套的 And
+func AndOf(nodes ...Node) Node {
+	var flat []Node
+	for _, n := range nodes {
+		switch v := n.(type) {
+		case nil:
+		case *And:
+			flat = append(flat, v.Nodes...)
+		default:
+			flat = append(flat, n)
+		}
+	}
+	return collapse(flat, func(ns []Node) Node { return &And{Nodes: ns} })
+}
+
+// OrOf 以 || 连接节点，忽略 nil 并展开嵌套的 Or
+func OrOf(nodes ...Node) Node {
+	var flat []Node
+	for _, n := range nodes {
+		switch v := n.(type) {
+		case nil:
+		case *Or:
+			flat = append(flat, v.Nodes...)
+		default:
+			flat = append(flat, n)
+		}
+	}
+	return collapse(flat, func(ns []Node) Node { return &Or{Nodes: ns} })
+}
+
+// NotOf 对节点取反
+func NotOf(n Node) Node {
+	if n == nil {
+		return nil
+	}
+	return &Not{Node: n}
+}
+
+func collapse(nodes []Node, build func([]Node) Node) Node {
+	switch len(nodes) {
+	case 0:
+		return nil
+	case 1:
+		return nodes[0]
+	}
+	return build(nodes)
+}
+
+// Escape 转义双引号字符串中的反斜杠与双引号
+func Escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
+
+// Quote 返回转义并加上双引号的字符串
+func Quote(value string) string {
+	return `"` + Escape(value) + `"`
+}
+
+// String 输出 FOFA 语法，语法树无法翻译为 FOFA 时 panic；
+// 来自用户输入的语法树应使用 Render(n, FOFA) 处理错误
+func String(n Node) string {
+	s, err := Render(n, FOFA)
+	if err != nil {
+		panic("query: " + err.Error())
+	}
+	return s
+}
+
+// generic 不做字段映射与取反下推的直接输出，供 Pretty 使用
+func generic(n Node) string {
+	switch v := n.(type) {
+	case *Cmp:
+		if v.Field == "" {
+			return Quote(v.Value)
+		}
+		return v.Field + string(v.Op) + Quote(v.Value)
+	case *And:
+		return joinGeneric(v.Nodes, " && ", 2)
+	case *Or:
+		return joinGeneric(v.Nodes, " || ", 1)
+	case *Not:
+		return "!" + wrapGeneric(v.Node, 3)
+	case *Group:
+		return "(" + generic(v.Node) + ")"
+	}
+	return ""
+}
+
+func joinGeneric(nodes []Node, sep string, prec int) string {
+	parts := make([]string, 0, len(nodes))
+	for _, c := range nodes {
+		parts = append(parts, wrapGeneric(c, prec))
+	}
+	return strings.Join(parts, sep)
+}
+
+func wrapGeneric(n Node, prec int) string {
+	if precedence(n) < prec {
+		return "(" + generic(n) + ")"
+	}
+	return generic(n)
+}
+
+// precedence 返回节点的结合优先级，数值越大结合越紧
+func precedence(n Node) int {
+	switch n.(type) {
+	case *Or:
+		return 1
+	case *And:
+		return 2
+	case *Not:
+		return 3
+	}
+	return 4
+}
+
+// Pretty 以缩进树的形式输出语法树，便于检查复杂查询
+func Pretty(n Node) string {
+	var b strings.Builder
+	pretty(&b, n, 0)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func pretty(b *strings.Builder, n Node, depth int) {
+	indent := strings.Repeat("  ", depth)
+	switch v := n.(type) {
+	case *Cmp:
+		b.WriteString(indent + generic(v) + "\n")
+	case *And:
+		b.WriteString(indent + "&&\n")
+		for _, c := range v.Nodes {
+			pretty(b, c, depth+1)
+		}
+	case *Or:
+		b.WriteString(indent + "||\n")
+		for _, c := range v.Nodes {
+			pretty(b, c, depth+1)
+		}
+	case *Not:
+		b.WriteString(indent + "!\n")
+		pretty(b, v.Node, depth+1)
+	case *Group:
+		b.WriteString(indent + "()\n")
+		pretty(b, v.Node, depth+1)
+	}
+}
+
+// Fields 返回语法树中出现的全部字段名（按出现顺序去重）
+func Fields(n Node) []string {
+	var fields []string
+	seen := make(map[string]bool)
+	var walk func(Node)
+	walk = func(n Node) {
+		switch v := n.(type) {
+		case *Cmp:
+			if !seen[v.Field] {
+				seen[v.Field] = true
+				fields = append(fields, v.Field)
+			}
+		case *And:
+			for _, c := range v.Nodes {
+				walk(c)
+			}
+		case *Or:
+			for _, c := range v.Nodes {
+				walk(c)
+			}
+		case *Not:
+			walk(v.Node)
+		case *Group:
+			walk(v.Node)
+		}
+	}
+	walk(n)
+	return fields
+}
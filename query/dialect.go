@@ -0,0 +1,407 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Dialect 描述一种测绘引擎的查询语法，语法树中的字段名以 FOFA 为准
+type Dialect struct {
+	Name string
+	// Fields 将 FOFA 字段映射为方言字段，为 nil 时字段原样输出；
+	// 映射值包含 %s 时作为全文检索模板，代入转义后的取值
+	Fields map[string]string
+	// Unsupported 在 Fields 为 nil 时列出方言不支持的字段
+	Unsupported []string
+	// Assign 为 "=" 时使用 FOFA 风格的比较运算符，否则以 field<Assign>value 表示等于
+	Assign string
+	// Ops 在 Assign 为 "=" 时改写 FOFA 比较运算符，未列出的原样输出
+	Ops map[Op]string
+	And string
+	// Require 是 && 中非取反子句的前缀，如 ZoomEye 的 +，此时空格连接的子句默认为 ||
+	Require string
+	// Or 为空表示方言不支持 ||
+	Or string
+	// Not 是取反前缀，为空时通过比较运算符与德摩根律下推取反
+	Not    string
+	Parens bool
+	// BareNumbers 为 true 时纯数字取值不加引号
+	BareNumbers bool
+}
+
+var (
+	FOFA = &Dialect{
+		Name:        "fofa",
+		Unsupported: []string{"icon_md5", "cert_sha256"},
+		Assign:      "=",
+		And:         " && ",
+		Or:          " || ",
+		Parens:      true,
+	}
+
+	Shodan = &Dialect{
+		Name: "shodan",
+		Fields: map[string]string{
+			"host":            "hostname",
+			"domain":          "hostname",
+			"title":           "http.title",
+			"icon_hash":       "http.favicon.hash",
+			"cert":            "ssl",
+			"cert.subject.cn": "ssl.cert.subject.cn",
+			"ip":              "net",
+			"port":            "port",
+			"org":             "org",
+			"country":         "country",
+			"city":            "city",
+			"body":            "http.html",
+			"server":          `"Server: %s"`,
+			"header":          `"%s"`,
+		},
+		Assign:      ":",
+		And:         " ",
+		Not:         "-",
+		BareNumbers: true,
+	}
+
+	ZoomEye = &Dialect{
+		Name: "zoomeye",
+		Fields: map[string]string{
+			"host":            "hostname",
+			"domain":          "hostname",
+			"title":           "title",
+			"icon_hash":       "iconhash",
+			"icon_md5":        "iconhash",
+			"cert":            "ssl",
+			"cert.subject.cn": "ssl",
+			"ip":              "cidr",
+			"port":            "port",
+			"country":         "country",
+			"city":            "city",
+		},
+		Assign:      ":",
+		And:         " ",
+		Require:     "+",
+		Or:          " ",
+		Not:         "-",
+		Parens:      true,
+		BareNumbers: true,
+	}
+
+	Censys = &Dialect{
+		Name: "censys",
+		Fields: map[string]string{
+			"host":            "dns.names",
+			"domain":          "dns.names",
+			"title":           "services.http.response.html_title",
+			"icon_md5":        "services.http.response.favicons.md5_hash",
+			"cert":            "services.tls.certificates.leaf_data.names",
+			"cert.subject.cn": "services.tls.certificates.leaf_data.names",
+			"cert_sha256":     "services.tls.certificates.leaf_data.fingerprint",
+			"ip":              "ip",
+			"port":            "services.port",
+			"org":             "autonomous_system.name",
+			"country":         "location.country_code",
+			"city":            "location.city",
+			"body":            "services.http.response.body",
+			"server":          "services.http.response.headers.server",
+		},
+		Assign: ": ",
+		And:    " and ",
+		Or:     " or ",
+		Not:    "not ",
+		Parens: true,
+	}
+
+	Hunter = &Dialect{
+		Name: "hunter",
+		Fields: map[string]string{
+			"host":            "domain",
+			"domain":          "domain",
+			"title":           "web.title",
+			"icon_md5":        "web.icon",
+			"cert":            "cert",
+			"cert.subject.cn": "cert.subject",
+			"ip":              "ip",
+			"port":            "ip.port",
+			"org":             "as.org",
+			"country":         "ip.country",
+			"city":            "ip.city",
+			"body":            "web.body",
+			"header":          "header",
+			"server":          "header.server",
+		},
+		Assign: "=",
+		// Hunter 的 = 即模糊匹配，没有 *=
+		Ops:    map[Op]string{OpFuzzy: "="},
+		And:    " && ",
+		Or:     " || ",
+		Parens: true,
+	}
+
+	Quake = &Dialect{
+		Name: "quake",
+		Fields: map[string]string{
+			"host":            "domain",
+			"domain":          "domain",
+			"title":           "title",
+			"icon_md5":        "favicon",
+			"cert":            "cert",
+			"cert.subject.cn": "cert",
+			"ip":              "ip",
+			"port":            "port",
+			"org":             "org",
+			"country":         "country",
+			"city":            "city",
+			"body":            "body",
+			"server":          "server",
+		},
+		Assign: ":",
+		And:    " AND ",
+		Or:     " OR ",
+		Not:    "NOT ",
+		Parens: true,
+	}
+)
+
+var dialects = map[string]*Dialect{
+	FOFA.Name:    FOFA,
+	Shodan.Name:  Shodan,
+	ZoomEye.Name: ZoomEye,
+	Censys.Name:  Censys,
+	Hunter.Name:  Hunter,
+	Quake.Name:   Quake,
+}
+
+// DialectByName 按引擎名称查找方言
+func DialectByName(name string) (*Dialect, bool) {
+	d, ok := dialects[strings.ToLower(name)]
+	return d, ok
+}
+
+// Supports 判断方言是否支持某个 FOFA 字段
+func (d *Dialect) Supports(field string) bool {
+	_, err := d.field(field)
+	return err == nil
+}
+
+func (d *Dialect) field(field string) (string, error) {
+	if d.Fields == nil {
+		for _, f := range d.Unsupported {
+			if f == field {
+				return "", fmt.Errorf("%s does not support field %q", d.Name, field)
+			}
+		}
+		return field, nil
+	}
+	mapped, ok := d.Fields[field]
+	if !ok {
+		return "", fmt.Errorf("%s does not support field %q", d.Name, field)
+	}
+	return mapped, nil
+}
+
+// Render 将语法树翻译为指定方言的查询语句
+func Render(n Node, d *Dialect) (string, error) {
+	if n == nil {
+		return "", nil
+	}
+	if d.Not == "" {
+		var err error
+		if n, err = pushNot(n, false); err != nil {
+			return "", fmt.Errorf("%s: %w", d.Name, err)
+		}
+	}
+	return d.render(n)
+}
+
+// pushNot 将取反下推到比较运算符上
+func pushNot(n Node, neg bool) (Node, error) {
+	switch v := n.(type) {
+	case *Cmp:
+		if !neg {
+			return v, nil
+		}
+		if v.Field == "" {
+			return nil, fmt.Errorf("cannot negate full-text search %s", Quote(v.Value))
+		}
+		c := *v
+		switch v.Op {
+		case OpEq, OpExact:
+			c.Op = OpNe
+		case OpNe:
+			c.Op = OpEq
+		default:
+			return nil, fmt.Errorf("cannot negate %s%s", v.Field, v.Op)
+		}
+		return &c, nil
+	case *And:
+		return pushNotList(v.Nodes, neg, true)
+	case *Or:
+		return pushNotList(v.Nodes, neg, false)
+	case *Not:
+		return pushNot(v.Node, !neg)
+	case *Group:
+		inner, err := pushNot(v.Node, neg)
+		if err != nil {
+			return nil, err
+		}
+		return &Group{Node: inner}, nil
+	}
+	return nil, fmt.Errorf("unknown node %T", n)
+}
+
+func pushNotList(nodes []Node, neg bool, isAnd bool) (Node, error) {
+	out := make([]Node, 0, len(nodes))
+	for _, c := range nodes {
+		pc, err := pushNot(c, neg)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pc)
+	}
+	// 德摩根律：取反后 && 与 || 互换
+	if isAnd != neg {
+		return &And{Nodes: out}, nil
+	}
+	return &Or{Nodes: out}, nil
+}
+
+var numberPattern = regexp.MustCompile(`^-?[0-9]+$`)
+
+func (d *Dialect) value(v string) string {
+	if d.BareNumbers && numberPattern.MatchString(v) {
+		return v
+	}
+	return Quote(v)
+}
+
+func (d *Dialect) render(n Node) (string, error) {
+	switch v := n.(type) {
+	case *Cmp:
+		return d.renderCmp(v)
+	case *And:
+		var b strings.Builder
+		for i, c := range v.Nodes {
+			part, err := d.wrap(c, 2)
+			if err != nil {
+				return "", err
+			}
+			if d.Require != "" && !strings.HasPrefix(part, d.Not) {
+				part = d.Require + part
+			}
+			if i > 0 {
+				b.WriteString(d.And)
+			}
+			b.WriteString(part)
+		}
+		return b.String(), nil
+	case *Or:
+		if d.Or == "" {
+			return "", fmt.Errorf("%s does not support ||", d.Name)
+		}
+		parts := make([]string, 0, len(v.Nodes))
+		for _, c := range v.Nodes {
+			prec := 1
+			if d.Require != "" {
+				// 必选前缀只在括号内生效，&& 子句必须加括号
+				prec = 3
+			}
+			part, err := d.wrap(c, prec)
+			if err != nil {
+				return "", err
+			}
+			if d.Require != "" && strings.HasPrefix(part, d.Not) {
+				return "", fmt.Errorf("%s cannot negate inside ||", d.Name)
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, d.Or), nil
+	case *Not:
+		if !d.Parens {
+			// 无括号时取反前缀只作用于紧随的一个子句
+			inner := v.Node
+			for g, ok := inner.(*Group); ok; g, ok = inner.(*Group) {
+				inner = g.Node
+			}
+			if _, ok := inner.(*Cmp); !ok {
+				return "", fmt.Errorf("%s cannot negate a group", d.Name)
+			}
+		}
+		inner, err := d.wrap(v.Node, 3)
+		if err != nil {
+			return "", err
+		}
+		return d.Not + inner, nil
+	case *Group:
+		inner, err := d.render(v.Node)
+		if err != nil {
+			return "", err
+		}
+		if !d.Parens {
+			if _, ok := v.Node.(*Cmp); !ok {
+				if _, ok := v.Node.(*And); !ok {
+					return "", fmt.Errorf("%s does not support grouping", d.Name)
+				}
+			}
+			return inner, nil
+		}
+		return "(" + inner + ")", nil
+	}
+	return "", fmt.Errorf("unknown node %T", n)
+}
+
+func (d *Dialect) wrap(n Node, prec int) (string, error) {
+	s, err := d.render(n)
+	if err != nil {
+		return "", err
+	}
+	if precedence(n) >= prec {
+		return s, nil
+	}
+	if !d.Parens {
+		return "", fmt.Errorf("%s does not support grouping", d.Name)
+	}
+	return "(" + s + ")", nil
+}
+
+func (d *Dialect) renderCmp(c *Cmp) (string, error) {
+	if c.Field == "" {
+		if c.Op == OpNe {
+			if d.Not == "" {
+				return "", fmt.Errorf("cannot negate full-text search %s", Quote(c.Value))
+			}
+			return d.Not + Quote(c.Value), nil
+		}
+		return Quote(c.Value), nil
+	}
+	field, err := d.field(c.Field)
+	if err != nil {
+		return "", err
+	}
+
+	if d.Assign == "=" {
+		if strings.Contains(field, "%s") {
+			return "", fmt.Errorf("%s cannot express field %q", d.Name, c.Field)
+		}
+		op := string(c.Op)
+		if mapped, ok := d.Ops[c.Op]; ok {
+			op = mapped
+		}
+		return field + op + Quote(c.Value), nil
+	}
+
+	var term string
+	if strings.Contains(field, "%s") {
+		term = fmt.Sprintf(field, Escape(c.Value))
+	} else {
+		term = field + d.Assign + d.value(c.Value)
+	}
+	switch c.Op {
+	case OpEq, OpExact, OpFuzzy:
+		return term, nil
+	case OpNe:
+		return d.Not + term, nil
+	}
+	return "", fmt.Errorf("%s does not support operator %s", d.Name, c.Op)
+}
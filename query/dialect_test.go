@@ -0,0 +1,93 @@
+package query
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		dialect *Dialect
+		input   string
+		want    string
+	}{
+		// 转义
+		{FOFA, `title="a\"b\\c"`, `title="a\"b\\c"`},
+		{Shodan, `title="a\"b\\c"`, `http.title:"a\"b\\c"`},
+		{Censys, `title="a\"b"`, `services.http.response.html_title: "a\"b"`},
+		{Shodan, `server="a\"b"`, `"Server: a\"b"`},
+
+		// 运算符
+		{FOFA, `title*="abc"`, `title*="abc"`},
+		{Hunter, `title*="abc"`, `web.title="abc"`},
+		{Hunter, `title=="abc"`, `web.title=="abc"`},
+		{Quake, `title*="abc"`, `title:"abc"`},
+		{Shodan, `port="443"`, `port:443`},
+
+		// && 与 ||
+		{Shodan, `title="a" && port="80"`, `http.title:"a" port:80`},
+		{ZoomEye, `title="a" && port="80"`, `+title:"a" +port:80`},
+		{ZoomEye, `title="a" || port="80"`, `title:"a" port:80`},
+		{ZoomEye, `title="a" && (port="80" || port="443")`, `+title:"a" +(port:80 port:443)`},
+		{ZoomEye, `title="a" && port="80" || city="x"`, `(+title:"a" +port:80) city:"x"`},
+		{ZoomEye, `title="a" && !port="80"`, `+title:"a" -port:80`},
+		{Censys, `title="a" || port="80"`, `services.http.response.html_title: "a" or services.port: "80"`},
+		{Quake, `title="a" && (port="80" || port="443")`, `title:"a" AND (port:"80" OR port:"443")`},
+
+		// 取反：有取反前缀时直接输出，否则按德摩根律下推
+		{Shodan, `!title="a"`, `-http.title:"a"`},
+		{Censys, `!(title="a" || port="80")`, `not (services.http.response.html_title: "a" or services.port: "80")`},
+		{Quake, `title="a" && !port="80"`, `title:"a" AND NOT port:"80"`},
+		{FOFA, `!(title="a" || port="80")`, `(title!="a" && port!="80")`},
+		{Hunter, `!(title="a" || port="80")`, `(web.title!="a" && ip.port!="80")`},
+		{Hunter, `!(title="a" && !port="80")`, `(web.title!="a" || ip.port="80")`},
+		{Hunter, `!!title="a"`, `web.title="a"`},
+	}
+	for _, tt := range tests {
+		n, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%s): %v", tt.input, err)
+		}
+		got, err := Render(n, tt.dialect)
+		if err != nil {
+			t.Errorf("Render(%s, %s): %v", tt.input, tt.dialect.Name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Render(%s, %s) = %s, want %s", tt.input, tt.dialect.Name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	tests := []struct {
+		dialect *Dialect
+		input   string
+		want    string
+	}{
+		// 每种方言不支持的字段
+		{FOFA, `icon_md5="abc"`, `does not support field "icon_md5"`},
+		{Shodan, `icon_md5="abc"`, `does not support field "icon_md5"`},
+		{ZoomEye, `org="abc"`, `does not support field "org"`},
+		{Censys, `icon_hash="123"`, `does not support field "icon_hash"`},
+		{Hunter, `icon_hash="123"`, `does not support field "icon_hash"`},
+		{Quake, `icon_hash="123"`, `does not support field "icon_hash"`},
+
+		// 方言无法表达的结构
+		{Shodan, `title="a" || port="80"`, "does not support ||"},
+		{Shodan, `!(title="a" && port="80")`, "cannot negate a group"},
+		{ZoomEye, `title="a" || !port="80"`, "cannot negate inside ||"},
+		{Hunter, `"abc" && !"def"`, "cannot negate full-text search"},
+		{FOFA, `!title*="abc"`, "cannot negate title*="},
+	}
+	for _, tt := range tests {
+		n, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%s): %v", tt.input, err)
+		}
+		got, err := Render(n, tt.dialect)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Render(%s, %s) = %s, %v, want error %q", tt.input, tt.dialect.Name, got, err, tt.want)
+		}
+	}
+}
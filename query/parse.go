@@ -0,0 +1,209 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// Parse 解析 FOFA 语法的查询语句，支持 = == != *=、&&、||、! 前缀取反与括号分组
+func Parse(input string) (Node, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, fmt.Errorf("empty query")
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+	return n, nil
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	rs := []rune(input)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case r == '&' && i+1 < len(rs) && rs[i+1] == '&':
+			tokens = append(tokens, token{tokAnd, "&&", i})
+			i += 2
+		case r == '|' && i+1 < len(rs) && rs[i+1] == '|':
+			tokens = append(tokens, token{tokOr, "||", i})
+			i += 2
+		case r == '=' && i+1 < len(rs) && rs[i+1] == '=':
+			tokens = append(tokens, token{tokOp, "==", i})
+			i += 2
+		case r == '=':
+			tokens = append(tokens, token{tokOp, "=", i})
+			i++
+		case (r == '!' || r == '*') && i+1 < len(rs) && rs[i+1] == '=':
+			tokens = append(tokens, token{tokOp, string(r) + "=", i})
+			i += 2
+		case r == '!':
+			tokens = append(tokens, token{tokNot, "!", i})
+			i++
+		case r == '"':
+			start := i
+			var b strings.Builder
+			i++
+			closed := false
+			for i < len(rs) {
+				if rs[i] == '\\' && i+1 < len(rs) {
+					b.WriteRune(rs[i+1])
+					i += 2
+					continue
+				}
+				if rs[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				b.WriteRune(rs[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			tokens = append(tokens, token{tokString, b.String(), start})
+		default:
+			start := i
+			for i < len(rs) && !unicode.IsSpace(rs[i]) && !strings.ContainsRune(`()"=!&|*`, rs[i]) {
+				i++
+			}
+			if i == start {
+				return nil, fmt.Errorf("unexpected %q at position %d", string(r), start)
+			}
+			tokens = append(tokens, token{tokIdent, string(rs[start:i]), start})
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(rs)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []Node{left}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, right)
+	}
+	return OrOf(nodes...), nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []Node{left}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, right)
+	}
+	return AndOf(nodes...), nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return NotOf(n), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokRParen {
+			return nil, fmt.Errorf("missing ) for ( at position %d", t.pos)
+		}
+		return &Group{Node: n}, nil
+	case tokString:
+		return Text(t.text), nil
+	case tokIdent:
+		op := p.next()
+		if op.kind != tokOp {
+			return nil, fmt.Errorf("expected operator after field %q at position %d", t.text, op.pos)
+		}
+		v := p.next()
+		if v.kind != tokString && v.kind != tokIdent {
+			return nil, fmt.Errorf("expected value after %s%s at position %d", t.text, op.text, v.pos)
+		}
+		return &Cmp{Field: t.text, Op: Op(op.text), Value: v.text}, nil
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+}
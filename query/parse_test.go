@@ -0,0 +1,103 @@
+package query
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{`abc`, `"abc"`},
+		{`a"b`, `"a\"b"`},
+		{`a\b`, `"a\\b"`},
+		{`\"`, `"\\\""`},
+		{``, `""`},
+	}
+	for _, tt := range tests {
+		if got := Quote(tt.value); got != tt.want {
+			t.Errorf("Quote(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`title="abc"`, `title="abc"`},
+		{`title = "abc"`, `title="abc"`},
+		{`title=="abc" && port!="80"`, `title=="abc" && port!="80"`},
+		{`title*="abc"`, `title*="abc"`},
+		{`"login"`, `"login"`},
+		{`title="a\"b"`, `title="a\"b"`},
+		{`title="a\\b"`, `title="a\\b"`},
+		{`title="a" || title="b" && port="80"`, `title="a" || title="b" && port="80"`},
+		{`(title="a" || title="b") && port="80"`, `(title="a" || title="b") && port="80"`},
+		{`!title="a"`, `title!="a"`},
+		{`!(title="a" || port="80")`, `(title!="a" && port!="80")`},
+	}
+	for _, tt := range tests {
+		n, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%s): %v", tt.input, err)
+			continue
+		}
+		if got := n.String(); got != tt.want {
+			t.Errorf("Parse(%s).String() = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseValueUnescaped(t *testing.T) {
+	n, err := Parse(`title="a\"b\\c"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := n.(*Cmp)
+	if !ok {
+		t.Fatalf("Parse returned %T, want *Cmp", n)
+	}
+	if c.Value != `a"b\c` {
+		t.Errorf("value = %q, want %q", c.Value, `a"b\c`)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{``, "empty query"},
+		{`title="abc`, "unterminated string"},
+		{`"abc\`, "unterminated string"},
+		{`(title="a"`, "missing )"},
+		{`title`, "expected operator"},
+		{`title="a" &&`, "unexpected end"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%s) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestStringPanicsOnInvalidFOFA(t *testing.T) {
+	n, err := Parse(`!title*="x"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Render(n, FOFA); err == nil {
+		t.Fatal("Render accepted a negated fuzzy match")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("String did not panic on a query FOFA cannot express")
+		}
+	}()
+	_ = n.String()
+}
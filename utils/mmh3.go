@@ -2,7 +2,9 @@ package utils
 
 import (
 	"bytes"
+	"crypto/md5"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"hash"
 	"io"
@@ -11,19 +13,42 @@ import (
 	"github.com/twmb/murmur3"
 )
 
+// IconHashes 同一个 favicon 的 mmh3（FOFA / Shodan）与 md5（ZoomEye）哈希
+type IconHashes struct {
+	MMH3 string
+	MD5  string
+}
+
 func GetIconHashFromURL(iconURL string) (string, error) {
-	resp, err := http.Get(iconURL)
+	hashes, err := GetIconHashesFromURL(iconURL)
 	if err != nil {
 		return "", err
 	}
+	return hashes.MMH3, nil
+}
+
+// GetIconHashesFromURL 下载 favicon 并同时计算 mmh3 与 md5 哈希
+func GetIconHashesFromURL(iconURL string) (IconHashes, error) {
+	resp, err := http.Get(iconURL)
+	if err != nil {
+		return IconHashes{}, err
+	}
 	defer resp.Body.Close()
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return IconHashes{}, err
 	}
 
-	return Mmh3Hash32(standardBase64(data)), nil
+	return IconHashes{
+		MMH3: Mmh3Hash32(standardBase64(data)),
+		MD5:  Md5Hash(data),
+	}, nil
+}
+
+func Md5Hash(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
 }
 
 func Mmh3Hash32(data []byte) string {
@@ -0,0 +1,368 @@
+package utils
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProvidersFile 是用户自定义的服务商定义文件，位于 GoUnder 配置目录，存在时替代内置定义
+const ProvidersFile = "cdn_providers.json"
+
+// 服务商未指定 cache_days 时 IP 段缓存的有效天数
+const defaultRangeCacheDays = 30
+
+//go:embed cdn_providers.json
+var builtinProviders []byte
+
+// RangeSource 是服务商公开的 IP 段列表
+type RangeSource struct {
+	URL string `json:"url"`
+	// Parser 为 lines（每行一个网段，# 开头为注释）、json（取 Fields 中列出的顶层字符串数组）、
+	// google（goog.json / cloud.json 的 prefixes）或 azure（Azure 服务标签文件中 Fields 列出的标签，
+	// URL 为下载页时先从页面中找到当前的 JSON 文件地址）
+	Parser string   `json:"parser"`
+	Fields []string `json:"fields,omitempty"`
+	// Form 不为空时以 POST 表单请求列表
+	Form map[string]string `json:"form,omitempty"`
+	// Exclude 为 true 时从其余列表中扣除该列表的网段
+	Exclude bool `json:"exclude,omitempty"`
+}
+
+// CDNProvider 描述一个 CDN 服务商，FOFA 排除规则与本地识别均由这些字段生成
+type CDNProvider struct {
+	// ID 用于缓存文件名与 dry-run 展示
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Ranges []RangeSource `json:"ranges,omitempty"`
+	// LocalOnly 为 true 时 IP 段只用于本地识别，列表过长，不生成 FOFA 排除规则
+	LocalOnly bool `json:"local_only,omitempty"`
+	// CacheDays 为 IP 段缓存的有效天数，各服务商列表的更新频率不同
+	CacheDays int `json:"cache_days,omitempty"`
+	// ASNs 生成 FOFA 排除规则；未配置 Ranges 时按 IP 到 ASN 的公开数据换算为本地识别用的网段，
+	// 只应列出 CDN 专用的 ASN，云厂商的 ASN 同时承载客户源站
+	ASNs []string `json:"asns,omitempty"`
+	// Orgs 与 CloudNames 只用于生成 FOFA 排除规则
+	Orgs       []string `json:"orgs,omitempty"`
+	CloudNames []string `json:"cloud_names,omitempty"`
+	// CNAMESuffixes 为接入该 CDN 后域名 CNAME 指向的后缀
+	CNAMESuffixes []string `json:"cname_suffixes,omitempty"`
+	// Servers 为节点返回的 Server 头关键字（小写），不超过 3 个字符的关键字（如 ws）需完整匹配
+	Servers []string `json:"servers,omitempty"`
+	// Headers 为节点特有的响应头，"name" 表示出现即命中，"name:value" 表示值中包含 value（小写）
+	Headers []string `json:"headers,omitempty"`
+	// FofaHeaders 为 FOFA 中按 header 排除的关键字
+	FofaHeaders []string `json:"fofa_headers,omitempty"`
+}
+
+// CDNGenericHeaders 是缓存或反向代理（包括源站前的 Squid、Varnish）常见的响应头，只说明经过代理，无法确定服务商
+var CDNGenericHeaders = []string{"x-cache", "via", "x-cdn", "x-served-by", "x-cache-hits", "cdn-cache", "x-cache-lookup", "x-edge-location"}
+
+var (
+	providersOnce sync.Once
+	providers     []CDNProvider
+)
+
+// CDNProviders 返回 CDN 服务商定义，配置目录下存在 cdn_providers.json 时使用该文件
+func CDNProviders() []CDNProvider {
+	providersOnce.Do(func() {
+		providers = loadProviders()
+	})
+	return providers
+}
+
+func loadProviders() []CDNProvider {
+	if path, err := getCacheFilePathFor(ProvidersFile); err == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			var custom []CDNProvider
+			err := json.Unmarshal(data, &custom)
+			if err == nil {
+				return custom
+			}
+			log.Printf("Invalid CDN provider file %s, using built-in definitions: %v\n", path, err)
+		}
+	}
+	var builtin []CDNProvider
+	if err := json.Unmarshal(builtinProviders, &builtin); err != nil {
+		panic(fmt.Sprintf("invalid built-in CDN providers: %v", err))
+	}
+	return builtin
+}
+
+type rangeCache struct {
+	CreateTime time.Time `json:"create_time"`
+	IPList     []string  `json:"ip_list"`
+	// Sources 记录生成缓存时的列表地址与字段，定义变化（如新增 IPv6 列表）后缓存失效
+	Sources []string `json:"sources,omitempty"`
+}
+
+// sourceKeys 返回 Ranges 的地址与字段（或换算网段所用的 ASN），用于判断缓存是否由当前定义生成
+func (p CDNProvider) sourceKeys() []string {
+	if len(p.Ranges) == 0 {
+		return []string{IPToASNURL + "#" + strings.Join(p.ASNs, ",")}
+	}
+	var keys []string
+	for _, src := range p.Ranges {
+		key := src.URL + "#" + strings.Join(src.Fields, ",")
+		if src.Exclude {
+			key = "-" + key
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// FofaRanges 判断 IP 段是否用于生成 FOFA 排除规则，按 ASN 换算的网段已由 asn 规则覆盖
+func (p CDNProvider) FofaRanges() bool {
+	return len(p.Ranges) > 0 && !p.LocalOnly
+}
+
+// LocallyRecognized 判断能否在本地按 IP 识别该服务商的节点，即配置了 IP 段列表或 ASN
+func (p CDNProvider) LocallyRecognized() bool {
+	return len(p.Ranges) > 0 || len(p.ASNs) > 0
+}
+
+// UnrecognizedProviders 返回无法在本地按 IP 识别的服务商名称，只能通过 CNAME 与响应头识别
+func UnrecognizedProviders() []string {
+	var names []string
+	for _, p := range CDNProviders() {
+		if !p.LocallyRecognized() {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+// IPRanges 返回服务商的 IP 段，并返回其来自本地缓存（cache）还是刚下载（download）；
+// 未配置 IP 段列表时按 ASN 换算，两者都没有时返回空
+func (p CDNProvider) IPRanges() ([]string, string, error) {
+	if !p.LocallyRecognized() {
+		return nil, "", nil
+	}
+	cacheFile, err := getCacheFilePathFor(p.ID + "_ips_cache.json")
+	if err != nil {
+		return nil, "", err
+	}
+
+	days := p.CacheDays
+	if days <= 0 {
+		days = defaultRangeCacheDays
+	}
+	if data, err := os.ReadFile(cacheFile); err == nil {
+		var cache rangeCache
+		if json.Unmarshal(data, &cache) == nil && time.Since(cache.CreateTime).Hours() < float64(days*24) &&
+			slices.Equal(cache.Sources, p.sourceKeys()) {
+			return cache.IPList, "cache", nil
+		}
+	}
+
+	var ipList, excluded []string
+	for _, src := range p.Ranges {
+		list, err := src.fetch()
+		if err != nil {
+			return nil, "", fmt.Errorf("下载 %s IP 列表失败: %v", p.Name, err)
+		}
+		if src.Exclude {
+			excluded = append(excluded, list...)
+		} else {
+			ipList = append(ipList, list...)
+		}
+	}
+	if len(p.Ranges) == 0 {
+		if ipList, err = asnPrefixes(p.ASNs); err != nil {
+			return nil, "", fmt.Errorf("获取 %s ASN 网段失败: %v", p.Name, err)
+		}
+	}
+	if len(excluded) > 0 {
+		ipList = subtractPrefixes(ipList, excluded)
+	}
+
+	cacheJSON, _ := json.MarshalIndent(rangeCache{CreateTime: time.Now(), IPList: ipList, Sources: p.sourceKeys()}, "", "  ")
+	_ = os.WriteFile(cacheFile, cacheJSON, 0644)
+	return ipList, "download", nil
+}
+
+// rangeClient 用于下载 IP 段列表，部分列表有数 MB
+var rangeClient = &http.Client{Timeout: 2 * time.Minute}
+
+// download 请求 url，form 不为空时以 POST 表单提交，非 200 时返回错误
+func download(url string, form map[string]string) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+	if len(form) > 0 {
+		values := neturl.Values{}
+		for k, v := range form {
+			values.Set(k, v)
+		}
+		resp, err = rangeClient.PostForm(url, values)
+	} else {
+		resp, err = rangeClient.Get(url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP 错误: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// azureServiceTagsURL 匹配 Azure 服务标签下载页中的 JSON 文件地址，文件名随每周更新变化
+var azureServiceTagsURL = regexp.MustCompile(`https://download\.microsoft\.com/download/[^"']+?/ServiceTags_Public_\d+\.json`)
+
+func (s RangeSource) fetch() ([]string, error) {
+	resp, err := download(s.URL, s.Form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var ipList []string
+	switch s.Parser {
+	case "google":
+		var doc struct {
+			Prefixes []struct {
+				IPv4Prefix string `json:"ipv4Prefix"`
+				IPv6Prefix string `json:"ipv6Prefix"`
+			} `json:"prefixes"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+			return nil, fmt.Errorf("解析 JSON 失败: %v", err)
+		}
+		for _, p := range doc.Prefixes {
+			if p.IPv4Prefix != "" {
+				ipList = append(ipList, p.IPv4Prefix)
+			}
+			if p.IPv6Prefix != "" {
+				ipList = append(ipList, p.IPv6Prefix)
+			}
+		}
+	case "azure":
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if link := azureServiceTagsURL.Find(data); link != nil && !json.Valid(data) {
+			tagsResp, err := download(string(link), nil)
+			if err != nil {
+				return nil, err
+			}
+			defer tagsResp.Body.Close()
+			if data, err = io.ReadAll(tagsResp.Body); err != nil {
+				return nil, err
+			}
+		}
+		var doc struct {
+			Values []struct {
+				Name       string `json:"name"`
+				Properties struct {
+					AddressPrefixes []string `json:"addressPrefixes"`
+				} `json:"properties"`
+			} `json:"values"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("解析 JSON 失败: %v", err)
+		}
+		for _, tag := range s.Fields {
+			found := false
+			for _, v := range doc.Values {
+				if v.Name == tag {
+					ipList = append(ipList, v.Properties.AddressPrefixes...)
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("服务标签文件中缺少 %s", tag)
+			}
+		}
+	case "json":
+		var doc map[string]json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+			return nil, fmt.Errorf("解析 JSON 失败: %v", err)
+		}
+		for _, field := range s.Fields {
+			raw, ok := doc[field]
+			if !ok {
+				return nil, fmt.Errorf("JSON 中缺少字段 %s", field)
+			}
+			var list []string
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return nil, fmt.Errorf("解析字段 %s 失败: %v", field, err)
+			}
+			ipList = append(ipList, list...)
+		}
+	case "lines", "":
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			ipList = append(ipList, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("未知的解析方式: %s", s.Parser)
+	}
+	return ipList, nil
+}
+
+// MatchCNAME 返回 CNAME 所属的 CDN 服务商及命中的后缀
+func MatchCNAME(name string) (*CDNProvider, string) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	list := CDNProviders()
+	for i, p := range list {
+		for _, suffix := range p.CNAMESuffixes {
+			if name == suffix || strings.HasSuffix(name, "."+suffix) {
+				return &list[i], suffix
+			}
+		}
+	}
+	return nil, ""
+}
+
+// MatchServer 返回 Server 头所属的 CDN 服务商
+func MatchServer(server string) *CDNProvider {
+	server = strings.ToLower(server)
+	if server == "" {
+		return nil
+	}
+	list := CDNProviders()
+	for i, p := range list {
+		for _, s := range p.Servers {
+			if server == s || strings.HasPrefix(server, s+"/") || (len(s) > 3 && strings.Contains(server, s)) {
+				return &list[i]
+			}
+		}
+	}
+	return nil
+}
+
+// MatchHeader 返回响应头所属的 CDN 服务商
+func MatchHeader(name, value string) *CDNProvider {
+	name, value = strings.ToLower(name), strings.ToLower(value)
+	list := CDNProviders()
+	for i, p := range list {
+		for _, sig := range p.Headers {
+			sigName, sigValue, _ := strings.Cut(sig, ":")
+			if name == sigName && strings.Contains(value, sigValue) {
+				return &list[i]
+			}
+		}
+	}
+	return nil
+}
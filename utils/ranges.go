@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+)
+
+// IPToASNURL 是 IP 段到 ASN 的公开数据（iptoasn.com），用于把服务商的 ASN 换算为网段。
+// 整表下载后在本地筛选，不会发送任何待判断的地址
+var IPToASNURL = "https://iptoasn.com/data/ip2asn-combined.tsv.gz"
+
+var (
+	asnTableOnce sync.Once
+	// asnTable 按 ASN 保存全部服务商 ASN 的网段
+	asnTable map[string][]string
+	asnErr   error
+)
+
+// asnPrefixes 返回 asns 在 IP 到 ASN 数据中的全部网段，数据表在一次运行中只下载一次
+func asnPrefixes(asns []string) ([]string, error) {
+	asnTableOnce.Do(func() {
+		wanted := make(map[string]bool)
+		for _, p := range CDNProviders() {
+			for _, asn := range p.ASNs {
+				wanted[asn] = true
+			}
+		}
+		asnTable, asnErr = loadASNTable(wanted)
+	})
+	if asnErr != nil {
+		return nil, asnErr
+	}
+	var prefixes []string
+	for _, asn := range asns {
+		prefixes = append(prefixes, asnTable[asn]...)
+	}
+	if len(prefixes) == 0 {
+		return nil, fmt.Errorf("ASN %s 没有网段记录", strings.Join(asns, ", "))
+	}
+	return prefixes, nil
+}
+
+// loadASNTable 下载 ip2asn 数据，只保留 wanted 中的 ASN。
+// 每行为制表符分隔的 起始地址、结束地址、ASN、国家、名称
+func loadASNTable(wanted map[string]bool) (map[string][]string, error) {
+	resp, err := download(IPToASNURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	table := make(map[string][]string)
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		cols := strings.Split(scanner.Text(), "\t")
+		if len(cols) < 3 || !wanted[cols[2]] {
+			continue
+		}
+		start, err1 := netip.ParseAddr(cols[0])
+		end, err2 := netip.ParseAddr(cols[1])
+		if err1 != nil || err2 != nil || start.Is4() != end.Is4() || end.Less(start) {
+			continue
+		}
+		for _, p := range rangeToPrefixes(start, end) {
+			table[cols[2]] = append(table[cols[2]], p.String())
+		}
+	}
+	return table, scanner.Err()
+}
+
+// rangeToPrefixes 将 start 到 end（含）的地址区间拆分为最少的 CIDR 网段
+func rangeToPrefixes(start, end netip.Addr) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for {
+		bits := start.BitLen()
+		// 在 start 仍为网段起点且不超出 end 的前提下尽量扩大网段
+		for bits > 0 {
+			wider := netip.PrefixFrom(start, bits-1)
+			if wider.Masked().Addr() != start || lastAddr(wider).Compare(end) > 0 {
+				break
+			}
+			bits--
+		}
+		p := netip.PrefixFrom(start, bits)
+		prefixes = append(prefixes, p)
+		last := lastAddr(p)
+		if last.Compare(end) >= 0 || !last.Next().IsValid() {
+			return prefixes
+		}
+		start = last.Next()
+	}
+}
+
+// lastAddr 返回网段的最后一个地址
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Masked().Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	last, _ := netip.AddrFromSlice(b)
+	return last
+}
+
+// subtractPrefixes 从 include 的网段中扣除 exclude 覆盖的地址，无法解析的网段原样保留
+func subtractPrefixes(include, exclude []string) []string {
+	var ex []netip.Prefix
+	for _, s := range exclude {
+		if p, err := netip.ParsePrefix(s); err == nil {
+			ex = append(ex, p.Masked())
+		}
+	}
+	var out []string
+	for _, s := range include {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			out = append(out, s)
+			continue
+		}
+		for _, rest := range subtractPrefix(p.Masked(), ex) {
+			out = append(out, rest.String())
+		}
+	}
+	return out
+}
+
+// subtractPrefix 将 p 对半拆分，直到每一半与 exclude 不相交或被完全覆盖
+func subtractPrefix(p netip.Prefix, exclude []netip.Prefix) []netip.Prefix {
+	for _, e := range exclude {
+		if !p.Overlaps(e) {
+			continue
+		}
+		if e.Bits() <= p.Bits() {
+			return nil
+		}
+		lo := netip.PrefixFrom(p.Addr(), p.Bits()+1)
+		b := p.Addr().AsSlice()
+		b[p.Bits()/8] |= 0x80 >> (p.Bits() % 8)
+		hiAddr, _ := netip.AddrFromSlice(b)
+		hi := netip.PrefixFrom(hiAddr, p.Bits()+1)
+		return append(subtractPrefix(lo, exclude), subtractPrefix(hi, exclude)...)
+	}
+	return []netip.Prefix{p}
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestRangeToPrefixes(t *testing.T) {
+	tests := []struct {
+		start, end string
+		want       []string
+	}{
+		{"1.2.3.0", "1.2.3.255", []string{"1.2.3.0/24"}},
+		{"1.2.3.4", "1.2.3.4", []string{"1.2.3.4/32"}},
+		{"10.0.0.1", "10.0.0.6", []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"}},
+		{"0.0.0.0", "255.255.255.255", []string{"0.0.0.0/0"}},
+		{"2001:db8::", "2001:db8::ffff", []string{"2001:db8::/112"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, p := range rangeToPrefixes(netip.MustParseAddr(tt.start), netip.MustParseAddr(tt.end)) {
+			got = append(got, p.String())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("rangeToPrefixes(%s, %s) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestSubtractPrefixes(t *testing.T) {
+	tests := []struct {
+		include, exclude []string
+		want             []string
+	}{
+		{[]string{"10.0.0.0/24"}, []string{"192.168.0.0/16"}, []string{"10.0.0.0/24"}},
+		{[]string{"10.0.0.0/24"}, []string{"10.0.0.0/16"}, nil},
+		{[]string{"10.0.0.0/24"}, []string{"10.0.0.128/25"}, []string{"10.0.0.0/25"}},
+		{[]string{"10.0.0.0/24"}, []string{"10.0.0.0/26"}, []string{"10.0.0.64/26", "10.0.0.128/25"}},
+		{[]string{"2001:db8::/32", "10.0.0.0/8"}, []string{"2001:db8:8000::/33"}, []string{"2001:db8::/33", "10.0.0.0/8"}},
+	}
+	for _, tt := range tests {
+		if got := subtractPrefixes(tt.include, tt.exclude); !slices.Equal(got, tt.want) {
+			t.Errorf("subtractPrefixes(%v, %v) = %v, want %v", tt.include, tt.exclude, got, tt.want)
+		}
+	}
+}
@@ -1,233 +1,211 @@
 package utils
 
 import (
-	"bufio"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
-	"strings"
-	"time"
-)
+	"sync"
 
-func FofaRules() string {
-	cloudfrontFilter, err := GetCloudFrontFOFAFilter()
-	if err != nil {
-		cloudfrontFilter = `header!="cloudfront"`
-	}
-	cloudflareFilter, err := GetCloudflareFOFAFilter()
-	if err != nil {
-		cloudflareFilter = `header!="cloudflare"`
-	}
-	return `&& server!="cloudflare" && server!="alicdn" && server!="qcloud"` +
-		` && server!="yunjiasu" && server!="yupaicloud" && cloud_name!="Cloudflare"` +
-		` && server!="upyun" && server!="ws" && server!="cdnws"` +
-		` && server!="china cache" && server!="fastly" && server!="akamai"` +
-		` && server!="akamaighost" && server!="hwcdn" && server!="Byte-nginx"` +
-		` && server!="wangzhansheshi" && server!="360wzws" && server!="incapsula"` +
-		` && server!="stackpath" && server!="keycdn" && cloud_name!="cloudfront"` +
-		` && org!="CLOUDFLARENET" && server!="layun.com" && server!="*cdn*" &&` +
-		cloudfrontFilter + ` && ` +
-		cloudflareFilter
-}
-func getCacheFilePath() (string, error) {
-	var baseDir string
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
+	"GoUnder/query"
+)
 
-	switch runtime.GOOS {
-	case "windows":
-		appData := os.Getenv("APPDATA")
-		if appData == "" {
-			// fallback to home dir
-			baseDir = filepath.Join(homeDir, "AppData", "Roaming", "GoUnder")
-		} else {
-			baseDir = filepath.Join(appData, "GoUnder")
-		}
-	case "darwin":
-		baseDir = filepath.Join(homeDir, "Library", "Application Support", "GoUnder")
-	default: // linux 和其他类 unix
-		// 你也可以用 XDG_CONFIG_HOME 环境变量，默认 ~/.config/GoUnder
-		xdgConfig := os.Getenv("XDG_CONFIG_HOME")
-		if xdgConfig == "" {
-			baseDir = filepath.Join(homeDir, ".config", "GoUnder")
-		} else {
-			baseDir = filepath.Join(xdgConfig, "GoUnder")
+// fuzzyServerMinLen 是按包含匹配 Server 头的引擎可用的最短关键字长度，
+// ws 等短关键字会误排除 awselb、nws 等大量站点
+const fuzzyServerMinLen = 4
+
+// cdnServerFilter 排除各服务商节点返回的 Server 头，fuzzy 为 true 时跳过短关键字
+func cdnServerFilter(fuzzy bool) query.Node {
+	var rules []query.Node
+	for _, p := range CDNProviders() {
+		for _, s := range p.Servers {
+			if fuzzy && len(s) < fuzzyServerMinLen {
+				continue
+			}
+			rules = append(rules, query.Ne("server", s))
 		}
 	}
-
-	// 确保目录存在
-	err = os.MkdirAll(baseDir, 0755)
-	if err != nil {
-		return "", err
-	}
-
-	return filepath.Join(baseDir, "cloudfront_ips_cache.json"), nil
-}
-
-const (
-	CloudFrontCacheFile     = "cloudfront_ips_cache.json"
-	CloudFrontCacheDays     = 30
-	CloudFrontAPIURL        = "https://d7uri8nf7uskq.cloudfront.net/tools/list-cloudfront-ips"
-	CloudflareCacheFileName = "cloudflare_ips_cache.json"
-	CloudflareCacheDays     = 30
-	CloudflareIPV4URL       = "https://www.cloudflare-cn.com/ips-v4/"
-)
-
-type CloudFrontCache struct {
-	CreateTime time.Time `json:"create_time"`
-	IPList     []string  `json:"ip_list"`
-}
-type CloudflareCache struct {
-	CreateTime time.Time `json:"create_time"`
-	IPList     []string  `json:"ip_list"`
+	return query.AndOf(rules...)
 }
 
-type CloudFrontAPIResponse struct {
-	IPv4Prefixes []string `json:"CLOUDFRONT_GLOBAL_IP_LIST"`
-	IPv6Prefixes []string `json:"CLOUDFRONT_GLOBAL_IP_LIST_IPV6"`
+// FilterPiece 是 FOFA 排除规则中的一段及其来源，供 dry-run 展示
+type FilterPiece struct {
+	// Source 为 builtin 或服务商 ID
+	Source string
+	// Origin 为 builtin（无 IP 段）、cache（本地 IP 段缓存）、download（刚下载）或 fallback（IP 段获取失败，只保留其余规则）
+	Origin string
+	Node   query.Node
 }
 
-// GetCloudFrontFOFAFilter 获取 CloudFront FOFA 过滤规则
-func GetCloudFrontFOFAFilter() (string, error) {
-	ipList, err := getCloudFrontIPs()
-	if err != nil {
-		return "", err
+// Rules 返回该段包含的条件数
+func (p FilterPiece) Rules() int {
+	if and, ok := p.Node.(*query.And); ok {
+		return len(and.Nodes)
 	}
-	return buildFOFAFilter(ipList), nil
+	return 1
 }
 
-// 获取 CloudFront IP 列表（带缓存）
-func getCloudFrontIPs() ([]string, error) {
-	cacheFile, err := getCacheFilePath()
-	if err != nil {
-		return nil, err
-	}
+// FofaRulePieces 按服务商拆分 FOFA 的 CDN 排除规则，每段由服务商定义中的 Server 头、header、云厂商、组织、ASN 与 IP 段生成，
+// local_only 的 IP 段与按 ASN 换算的网段不参与
+func FofaRulePieces() []FilterPiece {
+	pieces := []FilterPiece{{Source: "builtin", Origin: "builtin", Node: query.Ne("server", "*cdn*")}}
+	for _, p := range CDNProviders() {
+		var rules []query.Node
+		for _, s := range p.Servers {
+			rules = append(rules, query.Ne("server", s))
+		}
+		for _, h := range p.FofaHeaders {
+			rules = append(rules, query.Ne("header", h))
+		}
+		for _, c := range p.CloudNames {
+			rules = append(rules, query.Ne("cloud_name", c))
+		}
+		for _, o := range p.Orgs {
+			rules = append(rules, query.Ne("org", o))
+		}
+		for _, asn := range p.ASNs {
+			rules = append(rules, query.Ne("asn", asn))
+		}
 
-	// 读取缓存
-	if data, err := os.ReadFile(cacheFile); err == nil {
-		var cache CloudFrontCache
-		if json.Unmarshal(data, &cache) == nil {
-			if time.Since(cache.CreateTime).Hours() < CloudFrontCacheDays*24 {
-				return cache.IPList, nil
+		origin := "builtin"
+		if p.FofaRanges() {
+			origin = "fallback"
+			if ipList, o, err := p.IPRanges(); err == nil {
+				origin = o
+				for _, ip := range ipList {
+					rules = append(rules, query.Ne("ip", ip))
+				}
 			}
 		}
+		if len(rules) > 0 {
+			pieces = append(pieces, FilterPiece{Source: p.ID, Origin: origin, Node: query.AndOf(rules...)})
+		}
 	}
+	return pieces
+}
 
-	// 访问 CloudFront 官方 IP 列表
-	resp, err := http.Get(CloudFrontAPIURL)
-	if err != nil {
-		return nil, fmt.Errorf("下载 CloudFront IP 列表失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP 错误: %s", resp.Status)
+// FofaFilter 返回 FOFA 的 CDN 排除规则语法树
+func FofaFilter() query.Node {
+	var nodes []query.Node
+	for _, p := range FofaRulePieces() {
+		nodes = append(nodes, p.Node)
 	}
+	return query.AndOf(nodes...)
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+func FofaRules() string {
+	return `&& ` + FofaFilter().String()
+}
 
-	var apiResp CloudFrontAPIResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, fmt.Errorf("解析 CloudFront JSON 失败: %v", err)
-	}
+// ShodanRules 返回与 FofaRules 等价的 Shodan 排除规则，
+// 各服务商的 IP 段过长，由 IsCDNIP 在本地过滤
+func ShodanRules() string {
+	rules, _ := query.Render(query.AndOf(
+		cdnServerFilter(false),
+		query.Ne("org", "Cloudflare"),
+		query.NotOf(query.Text("X-Amz-Cf-Id")),
+	), query.Shodan)
+	return rules
+}
 
-	ipList := apiResp.IPv4Prefixes
+// HunterRules 返回与 FofaRules 等价的奇安信 Hunter 排除规则，
+// Hunter 的 != 表示不包含，只使用较长的 Server 关键字
+func HunterRules() string {
+	rules, _ := query.Render(cdnServerFilter(true), query.Hunter)
+	return `&& ` + rules
+}
 
-	// 写入缓存
-	cache := CloudFrontCache{
-		CreateTime: time.Now(),
-		IPList:     ipList,
+// QuakeRules 返回与 FofaRules 等价的 360 Quake 排除规则，
+// 各服务商的 IP 段过长，由 IsCDNIP 在本地过滤
+func QuakeRules() string {
+	// Quake 的 server 为模糊匹配，同样跳过短关键字
+	rules := []query.Node{cdnServerFilter(true)}
+	for _, p := range CDNProviders() {
+		for _, o := range p.Orgs {
+			rules = append(rules, query.Ne("org", o))
+		}
 	}
-	// 保存缓存
-	cacheJSON, _ := json.MarshalIndent(cache, "", "  ")
-	_ = os.WriteFile(cacheFile, cacheJSON, 0644)
-
-	return ipList, nil
+	q, _ := query.Render(query.AndOf(rules...), query.Quake)
+	return `AND ` + q
 }
 
-// 构建 FOFA 查询语句
-func buildFOFAFilter(ipList []string) string {
-	var filters []string
-	filters = append(filters, `header!="cloudfront"`)
-	for _, ip := range ipList {
-		filters = append(filters, fmt.Sprintf(`ip!="%s"`, ip))
+var (
+	cdnNetsOnce sync.Once
+	// cdnNets 按服务商名称保存已知的 CDN IP 段
+	cdnNets map[string][]*net.IPNet
+)
+
+// CDNIPRanges 返回全部服务商的 IP 段（带缓存）
+func CDNIPRanges() []string {
+	var ranges []string
+	for _, list := range cdnIPRangesByProvider() {
+		ranges = append(ranges, list...)
 	}
-	return strings.Join(filters, " && ")
+	return ranges
 }
 
-// GetCloudflareFOFAFilter 获取 Cloudflare FOFA 过滤规则
-func GetCloudflareFOFAFilter() (string, error) {
-	ipList, err := getCloudflareIPs()
-	if err != nil {
-		return "", err
+// cdnIPRangesByProvider 按服务商名称返回 IP 段，获取失败的服务商被跳过
+func cdnIPRangesByProvider() map[string][]string {
+	ranges := make(map[string][]string)
+	for _, p := range CDNProviders() {
+		if ipList, _, err := p.IPRanges(); err == nil && len(ipList) > 0 {
+			ranges[p.Name] = ipList
+		}
 	}
-	return buildFOFAFilter(ipList), nil
+	return ranges
 }
 
-// 获取 Cloudflare IP 列表（带缓存）
-func getCloudflareIPs() ([]string, error) {
-	cacheFile, err := getCacheFilePathFor("cloudflare_ips_cache.json")
-	if err != nil {
-		return nil, err
-	}
+// IsCDNIP 判断 IP 是否位于已知 CDN IP 段内
+func IsCDNIP(ip string) bool {
+	return CDNProviderOfIP(ip) != ""
+}
 
-	// 读取缓存
-	if data, err := os.ReadFile(cacheFile); err == nil {
-		var cache CloudflareCache
-		if json.Unmarshal(data, &cache) == nil {
-			if time.Since(cache.CreateTime).Hours() < CloudflareCacheDays*24 {
-				return cache.IPList, nil
+// CDNProviderOfIP 返回 IP 所属 CDN 服务商的名称，不属于已知 IP 段时返回空字符串；
+// 只有配置了 ranges 或 asns 的服务商能在本地识别，见 UnrecognizedProviders
+func CDNProviderOfIP(ip string) string {
+	cdnNetsOnce.Do(func() {
+		cdnNets = make(map[string][]*net.IPNet)
+		for name, list := range cdnIPRangesByProvider() {
+			for _, cidr := range list {
+				if _, n, err := net.ParseCIDR(cidr); err == nil {
+					cdnNets[name] = append(cdnNets[name], n)
+				}
 			}
 		}
-	}
-
-	// 访问 Cloudflare IPv4 列表
-	resp, err := http.Get(CloudflareIPV4URL)
-	if err != nil {
-		return nil, fmt.Errorf("下载 Cloudflare IPv4 列表失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP 错误: %s", resp.Status)
-	}
-
-	scanner := bufio.NewScanner(resp.Body)
-	var ipList []string
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	})
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	for name, nets := range cdnNets {
+		for _, n := range nets {
+			if n.Contains(parsed) {
+				return name
+			}
 		}
-		ipList = append(ipList, line)
 	}
+	return ""
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
+// CacheFilePath 返回 GoUnder 配置目录下指定文件的路径，供 cmd 保存状态与缓存文件
+func CacheFilePath(filename string) (string, error) {
+	return getCacheFilePathFor(filename)
+}
 
-	// 写入缓存
-	cache := CloudflareCache{
-		CreateTime: time.Now(),
-		IPList:     ipList,
+// getCacheFilePathFor 返回全局配置目录下指定缓存文件的路径，目录不存在时创建
+func getCacheFilePathFor(filename string) (string, error) {
+	baseDir, err := ConfigDir()
+	if err != nil {
+		return "", err
 	}
-	cacheJSON, _ := json.MarshalIndent(cache, "", "  ")
-	_ = os.WriteFile(cacheFile, cacheJSON, 0644)
-
-	return ipList, nil
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(baseDir, filename), nil
 }
 
-// getCacheFilePathFor 按系统获取指定缓存文件路径，复用之前的目录规则
-func getCacheFilePathFor(filename string) (string, error) {
+// ConfigDir 按系统返回 GoUnder 的全局配置目录，配置文件、状态与缓存文件都保存在这里，linux 下遵循 XDG_CONFIG_HOME
+func ConfigDir() (string, error) {
 	var baseDir string
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -252,9 +230,5 @@ func getCacheFilePathFor(filename string) (string, error) {
 			baseDir = filepath.Join(xdgConfig, "GoUnder")
 		}
 	}
-
-	if err := os.MkdirAll(baseDir, 0755); err != nil {
-		return "", err
-	}
-	return filepath.Join(baseDir, filename), nil
+	return baseDir, nil
 }
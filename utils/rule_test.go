@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFuzzyRulesSkipShortServers(t *testing.T) {
+	// 使用内置服务商定义，不读取本机配置目录
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tests := []struct {
+		name    string
+		rules   string
+		present string
+		absent  []string
+	}{
+		{"hunter", HunterRules(), `header.server!="cdnws"`, []string{`header.server!="ws"`, `header.server!="nws"`}},
+		{"quake", QuakeRules(), `NOT server:"cdnws"`, []string{`server:"ws"`, `server:"nws"`}},
+	}
+	for _, tt := range tests {
+		if !strings.Contains(tt.rules, tt.present) {
+			t.Errorf("%s rules missing %s: %s", tt.name, tt.present, tt.rules)
+		}
+		for _, s := range tt.absent {
+			if strings.Contains(tt.rules, s) {
+				t.Errorf("%s rules contain short keyword %s: %s", tt.name, s, tt.rules)
+			}
+		}
+	}
+}